@@ -1,15 +1,26 @@
 package main
 
 import (
+	"context"
 	"echto/internal/config"
 	"echto/internal/database"
 	"echto/internal/handler"
 	"echto/internal/repository"
 	routes "echto/internal/route"
 	"echto/internal/service"
+	"echto/pkg/encryption"
+	"echto/pkg/i18n"
 	"echto/pkg/logger"
 	echtoMiddleware "echto/pkg/middleware"
+	"echto/pkg/notifier"
+	"echto/pkg/oidc"
+	"echto/pkg/password"
+	"echto/pkg/problem"
+	"echto/pkg/token"
+	"echto/pkg/validation"
 	"fmt"
+	"strings"
+	"time"
 
 	_ "echto/pkg/swagger"
 
@@ -39,6 +50,11 @@ func main() {
 	// Initialize logger
 	logger.Init(cfg.Logging.LOG_LEVEL, cfg.Logging.LOG_FORMAT)
 
+	// Refuse placeholder secrets outside development
+	if err := cfg.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid configuration")
+	}
+
 	// Initialize database
 	db := database.Init(cfg.Database)
 
@@ -47,31 +63,185 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to run database migrations")
 	}
 
+	// Load message catalogs
+	catalog, err := i18n.Load(cfg.I18n.I18N_LOCALES_DIR)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load message catalogs")
+	}
+
 	// Initialize Echo
 	e := echo.New()
+	e.HTTPErrorHandler = problem.HTTPErrorHandler
+	e.Validator = validation.New()
+
+	// Take the client IP from X-Forwarded-For only behind a trusted proxy
+	e.IPExtractor, err = handler.NewIPExtractor(cfg.App.TRUSTED_PROXIES)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid TRUSTED_PROXIES")
+	}
 
 	// Middleware
+	e.Use(middleware.RequestID())
+	e.Use(catalog.Middleware())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
 	e.Use(middleware.Gzip())
-	e.Use(middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(20)))
 
 	// Custom middleware
+	e.Use(echtoMiddleware.RateLimiter(middleware.NewRateLimiterMemoryStore(20)))
 	e.Use(echtoMiddleware.RequestLogger())
-	e.Use(middleware.RequestID())
+
+	// Bound the time every request may spend on database queries; 0 disables the limit
+	queryTimeout, err := time.ParseDuration(cfg.Database.DB_QUERY_TIMEOUT)
+	if err != nil {
+		log.Fatal().Err(err).Str("value", cfg.Database.DB_QUERY_TIMEOUT).Msg("Invalid DB_QUERY_TIMEOUT")
+	}
+	if queryTimeout > 0 {
+		e.Use(echtoMiddleware.QueryTimeout(queryTimeout))
+	}
 
 	// Initialize repository
 	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revocationRepo := repository.NewRevocationRepository(db)
+	passwordResetTokenRepo := repository.NewPasswordResetTokenRepository(db)
+	emailVerificationTokenRepo := repository.NewEmailVerificationTokenRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	loginFailureRepo := repository.NewLoginFailureRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	linkedIdentityRepo := repository.NewLinkedIdentityRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	transactor := repository.NewTransactor(db)
+
+	// Initialize token manager
+	tokenManager := token.NewManager(cfg.JWT.JWT_SECRET, time.Duration(cfg.JWT.JWT_EXPIRE_HOURS)*time.Hour)
+
+	// Initialize TOTP secret cipher
+	totpCipher, err := encryption.NewCipher(cfg.Auth.TOTP_ENCRYPTION_KEY)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize TOTP secret cipher")
+	}
+
+	// Initialize password hasher
+	passwordHasher, err := password.NewHasher(password.Config{
+		Algorithm:         cfg.Password.PASSWORD_HASH_ALGORITHM,
+		BcryptCost:        cfg.Password.PASSWORD_BCRYPT_COST,
+		Argon2Memory:      uint32(cfg.Password.PASSWORD_ARGON2_MEMORY_KB),
+		Argon2Iterations:  uint32(cfg.Password.PASSWORD_ARGON2_ITERATIONS),
+		Argon2Parallelism: uint8(cfg.Password.PASSWORD_ARGON2_PARALLELISM),
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize password hasher")
+	}
+
+	// Initialize password policy
+	passwordPolicy, err := password.NewPolicy(password.PolicyConfig{
+		MinLength:        cfg.Password.PASSWORD_MIN_LENGTH,
+		MaxLength:        cfg.Password.PASSWORD_MAX_LENGTH,
+		MaxBytes:         passwordHasher.MaxPasswordBytes(),
+		RequireUpper:     cfg.Password.PASSWORD_REQUIRE_UPPER,
+		RequireLower:     cfg.Password.PASSWORD_REQUIRE_LOWER,
+		RequireDigit:     cfg.Password.PASSWORD_REQUIRE_DIGIT,
+		RequireSymbol:    cfg.Password.PASSWORD_REQUIRE_SYMBOL,
+		DisallowUserInfo: cfg.Password.PASSWORD_DISALLOW_USER_INFO,
+		BreachedListPath: cfg.Password.PASSWORD_BREACHED_LIST_PATH,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize password policy")
+	}
+	log.Info().Int("breached_passwords", passwordPolicy.BreachedCount()).Msg("Password policy loaded")
+
+	// Initialize OpenID Connect providers
+	oidcStateCipher, err := encryption.NewCipher(cfg.OIDC.OIDC_STATE_KEY)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize OIDC state cipher")
+	}
+	oidcProviders := map[string]oidc.Provider{}
+	if cfg.OIDC.OIDC_ISSUER_URL != "" {
+		oidcProviders[cfg.OIDC.OIDC_PROVIDER_NAME] = oidc.NewClient(oidc.Config{
+			IssuerURL:    cfg.OIDC.OIDC_ISSUER_URL,
+			ClientID:     cfg.OIDC.OIDC_CLIENT_ID,
+			ClientSecret: cfg.OIDC.OIDC_CLIENT_SECRET,
+			RedirectURL:  cfg.OIDC.OIDC_REDIRECT_URL,
+			Scopes:       strings.Fields(cfg.OIDC.OIDC_SCOPES),
+		})
+	}
+
+	// Initialize notifier
+	userNotifier := notifier.New(cfg.Notifier.NOTIFIER_DRIVER, cfg.Notifier.NOTIFIER_FILE_PATH)
 
 	// Initialize service
-	userService := service.NewUserService(userRepo)
+	emailVerificationService := service.NewEmailVerificationService(userRepo, emailVerificationTokenRepo, userNotifier, time.Duration(cfg.Auth.EMAIL_VERIFICATION_EXPIRE_HOURS)*time.Hour, cfg.Auth.EMAIL_VERIFICATION_URL)
+	twoFactorService := service.NewTwoFactorService(userRepo, recoveryCodeRepo, totpCipher, cfg.Auth.TOTP_ISSUER)
+	loginThrottleService := service.NewLoginThrottleService(loginFailureRepo, service.LoginThrottleConfig{
+		MaxAccountFailures: cfg.Auth.LOGIN_MAX_FAILURES,
+		MaxIPFailures:      cfg.Auth.LOGIN_MAX_FAILURES_PER_IP,
+		LockoutDuration:    time.Duration(cfg.Auth.LOGIN_LOCKOUT_MINUTES) * time.Minute,
+		BackoffBase:        time.Duration(cfg.Auth.LOGIN_BACKOFF_BASE_SECONDS) * time.Second,
+		BackoffMax:         time.Duration(cfg.Auth.LOGIN_BACKOFF_MAX_SECONDS) * time.Second,
+	})
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, revocationRepo, sessionRepo, twoFactorService, loginThrottleService, passwordHasher, passwordPolicy, tokenManager, service.AuthServiceConfig{
+		RefreshTTL:           time.Duration(cfg.JWT.JWT_REFRESH_EXPIRE_HOURS) * time.Hour,
+		RequireVerifiedEmail: cfg.Auth.REQUIRE_VERIFIED_EMAIL,
+		ImpersonationTTL:     time.Duration(cfg.Auth.IMPERSONATION_EXPIRE_MINUTES) * time.Minute,
+	})
+	userService := service.NewUserService(userRepo, emailVerificationService, passwordHasher, passwordPolicy, authService, cfg.App.REQUIRE_IF_MATCH)
+	apiKeyService := service.NewAPIKeyService(userRepo, apiKeyRepo)
+	sessionService := service.NewSessionService(sessionRepo, refreshTokenRepo)
+	oidcService := service.NewOIDCService(userRepo, linkedIdentityRepo, authService, emailVerificationService, passwordHasher, oidcStateCipher, oidcProviders)
+	passwordResetService := service.NewPasswordResetService(userRepo, passwordResetTokenRepo, transactor, authService, userNotifier, passwordHasher, passwordPolicy, time.Duration(cfg.Auth.PASSWORD_RESET_EXPIRE_MINUTES)*time.Minute, cfg.Auth.PASSWORD_RESET_URL)
 
 	// Initialize handler
 	userHandler := handler.NewUserHandler(userService)
+	authHandler := handler.NewAuthHandler(authService)
+	passwordResetHandler := handler.NewPasswordResetHandler(passwordResetService)
+	emailVerificationHandler := handler.NewEmailVerificationHandler(emailVerificationService)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	oidcHandler := handler.NewOIDCHandler(oidcService)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+
+	// Auth middleware
+	auth := echtoMiddleware.JWTAuthWithConfig(echtoMiddleware.JWTAuthConfig{
+		Tokens:      tokenManager,
+		Revocations: revocationRepo,
+		Sessions:    sessionService,
+	})
+
+	// Auth middleware that also accepts API keys, for routes machine clients may call
+	apiKeyAuth := echtoMiddleware.JWTAuthWithConfig(echtoMiddleware.JWTAuthConfig{
+		Tokens:      tokenManager,
+		Revocations: revocationRepo,
+		APIKeys:     apiKeyService,
+		Sessions:    sessionService,
+	})
+
+	// Purge expired revocation entries, stale login failure counters and sessions whose refresh tokens expired
+	go func() {
+		ctx := context.Background()
+		for range time.Tick(time.Hour) {
+			if err := revocationRepo.DeleteExpired(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to purge expired token revocations")
+			}
+			if err := loginFailureRepo.DeleteStale(ctx, time.Now().Add(-time.Duration(cfg.Auth.LOGIN_LOCKOUT_MINUTES)*time.Minute)); err != nil {
+				log.Error().Err(err).Msg("Failed to purge stale login failures")
+			}
+			if err := sessionRepo.DeleteInactive(ctx, time.Now().Add(-time.Duration(cfg.JWT.JWT_REFRESH_EXPIRE_HOURS)*time.Hour)); err != nil {
+				log.Error().Err(err).Msg("Failed to purge inactive sessions")
+			}
+		}
+	}()
 
 	// Routes
-	routes.UserRoute(e, userHandler)
+	routes.AuthRoute(e, authHandler, auth)
+	routes.OIDCRoute(e, oidcHandler)
+	routes.PasswordResetRoute(e, passwordResetHandler)
+	routes.EmailVerificationRoute(e, emailVerificationHandler)
+	routes.TwoFactorRoute(e, twoFactorHandler, auth)
+	routes.APIKeyRoute(e, apiKeyHandler, auth)
+	routes.SessionRoute(e, sessionHandler, auth)
+	routes.UserRoute(e, userHandler, apiKeyAuth)
 	routes.SwaggerRoute(e)
 
 	// Start server
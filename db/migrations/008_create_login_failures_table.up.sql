@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS login_failures (
+    id SERIAL PRIMARY KEY,
+    key VARCHAR(320) UNIQUE NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_linked_identities_user_id;
+DROP INDEX IF EXISTS idx_linked_identities_provider_subject;
+DROP TABLE IF EXISTS linked_identities;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_sessions_last_seen_at;
+DROP INDEX IF EXISTS idx_sessions_user_id;
+DROP INDEX IF EXISTS idx_sessions_family_id;
+DROP TABLE IF EXISTS sessions;
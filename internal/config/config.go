@@ -2,16 +2,29 @@ package config
 
 import (
 	"echto/pkg/logger"
+	"fmt"
 	"strings"
 
 	"github.com/spf13/viper"
 )
 
+// Placeholder secrets used by default, which are only acceptable in development
+const (
+	defaultJWTSecret         = "your-secret-key"
+	defaultTOTPEncryptionKey = "your-totp-encryption-key"
+	defaultOIDCStateKey      = "your-oidc-state-key"
+)
+
 type Config struct {
 	App      AppConfig      `mapstructure:"app"`
 	Database DatabaseConfig `mapstructure:"database"`
 	Logging  LoggingConfig  `mapstructure:"logging"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
+	Auth     AuthConfig     `mapstructure:"auth"`
+	Password PasswordConfig `mapstructure:"password"`
+	Notifier NotifierConfig `mapstructure:"notifier"`
+	OIDC     OIDCConfig     `mapstructure:"oidc"`
+	I18n     I18nConfig     `mapstructure:"i18n"`
 }
 
 type AppConfig struct {
@@ -19,6 +32,11 @@ type AppConfig struct {
 	APP_NAME string `mapstructure:"APP_NAME"`
 	APP_PORT int    `mapstructure:"APP_PORT"`
 	APP_HOST string `mapstructure:"APP_HOST"`
+	// REQUIRE_IF_MATCH refuses user updates and deletes without an If-Match header
+	REQUIRE_IF_MATCH bool `mapstructure:"REQUIRE_IF_MATCH"`
+	// TRUSTED_PROXIES lists the CIDR ranges of reverse proxies whose X-Forwarded-For is
+	// trusted for the client IP; when empty the address of the connection is used
+	TRUSTED_PROXIES string `mapstructure:"TRUSTED_PROXIES"`
 }
 
 type DatabaseConfig struct {
@@ -31,6 +49,7 @@ type DatabaseConfig struct {
 	DB_MAX_IDLE_CONNS    int    `mapstructure:"DB_MAX_IDLE_CONNS"`
 	DB_MAX_OPEN_CONNS    int    `mapstructure:"DB_MAX_OPEN_CONNS"`
 	DB_CONN_MAX_LIFETIME string `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	DB_QUERY_TIMEOUT     string `mapstructure:"DB_QUERY_TIMEOUT"`
 }
 
 type LoggingConfig struct {
@@ -39,8 +58,60 @@ type LoggingConfig struct {
 }
 
 type JWTConfig struct {
-	JWT_SECRET       string `mapstructure:"JWT_SECRET"`
-	JWT_EXPIRE_HOURS int    `mapstructure:"JWT_EXPIRE_HOURS"`
+	JWT_SECRET               string `mapstructure:"JWT_SECRET"`
+	JWT_EXPIRE_HOURS         int    `mapstructure:"JWT_EXPIRE_HOURS"`
+	JWT_REFRESH_EXPIRE_HOURS int    `mapstructure:"JWT_REFRESH_EXPIRE_HOURS"`
+}
+
+type AuthConfig struct {
+	PASSWORD_RESET_EXPIRE_MINUTES   int    `mapstructure:"PASSWORD_RESET_EXPIRE_MINUTES"`
+	PASSWORD_RESET_URL              string `mapstructure:"PASSWORD_RESET_URL"`
+	EMAIL_VERIFICATION_EXPIRE_HOURS int    `mapstructure:"EMAIL_VERIFICATION_EXPIRE_HOURS"`
+	EMAIL_VERIFICATION_URL          string `mapstructure:"EMAIL_VERIFICATION_URL"`
+	REQUIRE_VERIFIED_EMAIL          bool   `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	TOTP_ISSUER                     string `mapstructure:"TOTP_ISSUER"`
+	TOTP_ENCRYPTION_KEY             string `mapstructure:"TOTP_ENCRYPTION_KEY"`
+	LOGIN_MAX_FAILURES              int    `mapstructure:"LOGIN_MAX_FAILURES"`
+	LOGIN_MAX_FAILURES_PER_IP       int    `mapstructure:"LOGIN_MAX_FAILURES_PER_IP"`
+	LOGIN_LOCKOUT_MINUTES           int    `mapstructure:"LOGIN_LOCKOUT_MINUTES"`
+	LOGIN_BACKOFF_BASE_SECONDS      int    `mapstructure:"LOGIN_BACKOFF_BASE_SECONDS"`
+	LOGIN_BACKOFF_MAX_SECONDS       int    `mapstructure:"LOGIN_BACKOFF_MAX_SECONDS"`
+	IMPERSONATION_EXPIRE_MINUTES    int    `mapstructure:"IMPERSONATION_EXPIRE_MINUTES"`
+}
+
+type PasswordConfig struct {
+	PASSWORD_HASH_ALGORITHM     string `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	PASSWORD_BCRYPT_COST        int    `mapstructure:"PASSWORD_BCRYPT_COST"`
+	PASSWORD_ARGON2_MEMORY_KB   int    `mapstructure:"PASSWORD_ARGON2_MEMORY_KB"`
+	PASSWORD_ARGON2_ITERATIONS  int    `mapstructure:"PASSWORD_ARGON2_ITERATIONS"`
+	PASSWORD_ARGON2_PARALLELISM int    `mapstructure:"PASSWORD_ARGON2_PARALLELISM"`
+	PASSWORD_MIN_LENGTH         int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PASSWORD_MAX_LENGTH         int    `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PASSWORD_REQUIRE_UPPER      bool   `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PASSWORD_REQUIRE_LOWER      bool   `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PASSWORD_REQUIRE_DIGIT      bool   `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PASSWORD_REQUIRE_SYMBOL     bool   `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PASSWORD_DISALLOW_USER_INFO bool   `mapstructure:"PASSWORD_DISALLOW_USER_INFO"`
+	PASSWORD_BREACHED_LIST_PATH string `mapstructure:"PASSWORD_BREACHED_LIST_PATH"`
+}
+
+type NotifierConfig struct {
+	NOTIFIER_DRIVER    string `mapstructure:"NOTIFIER_DRIVER"`
+	NOTIFIER_FILE_PATH string `mapstructure:"NOTIFIER_FILE_PATH"`
+}
+
+type OIDCConfig struct {
+	OIDC_PROVIDER_NAME string `mapstructure:"OIDC_PROVIDER_NAME"`
+	OIDC_ISSUER_URL    string `mapstructure:"OIDC_ISSUER_URL"`
+	OIDC_CLIENT_ID     string `mapstructure:"OIDC_CLIENT_ID"`
+	OIDC_CLIENT_SECRET string `mapstructure:"OIDC_CLIENT_SECRET"`
+	OIDC_REDIRECT_URL  string `mapstructure:"OIDC_REDIRECT_URL"`
+	OIDC_SCOPES        string `mapstructure:"OIDC_SCOPES"`
+	OIDC_STATE_KEY     string `mapstructure:"OIDC_STATE_KEY"`
+}
+
+type I18nConfig struct {
+	I18N_LOCALES_DIR string `mapstructure:"I18N_LOCALES_DIR"`
 }
 
 func Load() *Config {
@@ -64,10 +135,12 @@ func Load() *Config {
 
 	var config Config = Config{
 		App: AppConfig{
-			APP_ENV:  viper.GetString("APP_ENV"),
-			APP_NAME: viper.GetString("APP_NAME"),
-			APP_PORT: viper.GetInt("APP_PORT"),
-			APP_HOST: viper.GetString("APP_HOST"),
+			APP_ENV:          viper.GetString("APP_ENV"),
+			APP_NAME:         viper.GetString("APP_NAME"),
+			APP_PORT:         viper.GetInt("APP_PORT"),
+			APP_HOST:         viper.GetString("APP_HOST"),
+			REQUIRE_IF_MATCH: viper.GetBool("REQUIRE_IF_MATCH"),
+			TRUSTED_PROXIES:  viper.GetString("TRUSTED_PROXIES"),
 		},
 		Database: DatabaseConfig{
 			DB_HOST:              viper.GetString("DB_HOST"),
@@ -79,20 +152,94 @@ func Load() *Config {
 			DB_MAX_IDLE_CONNS:    viper.GetInt("DB_MAX_IDLE_CONNS"),
 			DB_MAX_OPEN_CONNS:    viper.GetInt("DB_MAX_OPEN_CONNS"),
 			DB_CONN_MAX_LIFETIME: viper.GetString("DB_CONN_MAX_LIFETIME"),
+			DB_QUERY_TIMEOUT:     viper.GetString("DB_QUERY_TIMEOUT"),
 		},
 		Logging: LoggingConfig{
 			LOG_LEVEL:  viper.GetString("LOG_LEVEL"),
 			LOG_FORMAT: viper.GetString("LOG_FORMAT"),
 		},
 		JWT: JWTConfig{
-			JWT_SECRET:       viper.GetString("JWT_SECRET"),
-			JWT_EXPIRE_HOURS: viper.GetInt("JWT_EXPIRE_HOURS"),
+			JWT_SECRET:               viper.GetString("JWT_SECRET"),
+			JWT_EXPIRE_HOURS:         viper.GetInt("JWT_EXPIRE_HOURS"),
+			JWT_REFRESH_EXPIRE_HOURS: viper.GetInt("JWT_REFRESH_EXPIRE_HOURS"),
+		},
+		Auth: AuthConfig{
+			PASSWORD_RESET_EXPIRE_MINUTES:   viper.GetInt("PASSWORD_RESET_EXPIRE_MINUTES"),
+			PASSWORD_RESET_URL:              viper.GetString("PASSWORD_RESET_URL"),
+			EMAIL_VERIFICATION_EXPIRE_HOURS: viper.GetInt("EMAIL_VERIFICATION_EXPIRE_HOURS"),
+			EMAIL_VERIFICATION_URL:          viper.GetString("EMAIL_VERIFICATION_URL"),
+			REQUIRE_VERIFIED_EMAIL:          viper.GetBool("REQUIRE_VERIFIED_EMAIL"),
+			TOTP_ISSUER:                     viper.GetString("TOTP_ISSUER"),
+			TOTP_ENCRYPTION_KEY:             viper.GetString("TOTP_ENCRYPTION_KEY"),
+			LOGIN_MAX_FAILURES:              viper.GetInt("LOGIN_MAX_FAILURES"),
+			LOGIN_MAX_FAILURES_PER_IP:       viper.GetInt("LOGIN_MAX_FAILURES_PER_IP"),
+			LOGIN_LOCKOUT_MINUTES:           viper.GetInt("LOGIN_LOCKOUT_MINUTES"),
+			LOGIN_BACKOFF_BASE_SECONDS:      viper.GetInt("LOGIN_BACKOFF_BASE_SECONDS"),
+			LOGIN_BACKOFF_MAX_SECONDS:       viper.GetInt("LOGIN_BACKOFF_MAX_SECONDS"),
+			IMPERSONATION_EXPIRE_MINUTES:    viper.GetInt("IMPERSONATION_EXPIRE_MINUTES"),
+		},
+		Password: PasswordConfig{
+			PASSWORD_HASH_ALGORITHM:     viper.GetString("PASSWORD_HASH_ALGORITHM"),
+			PASSWORD_BCRYPT_COST:        viper.GetInt("PASSWORD_BCRYPT_COST"),
+			PASSWORD_ARGON2_MEMORY_KB:   viper.GetInt("PASSWORD_ARGON2_MEMORY_KB"),
+			PASSWORD_ARGON2_ITERATIONS:  viper.GetInt("PASSWORD_ARGON2_ITERATIONS"),
+			PASSWORD_ARGON2_PARALLELISM: viper.GetInt("PASSWORD_ARGON2_PARALLELISM"),
+			PASSWORD_MIN_LENGTH:         viper.GetInt("PASSWORD_MIN_LENGTH"),
+			PASSWORD_MAX_LENGTH:         viper.GetInt("PASSWORD_MAX_LENGTH"),
+			PASSWORD_REQUIRE_UPPER:      viper.GetBool("PASSWORD_REQUIRE_UPPER"),
+			PASSWORD_REQUIRE_LOWER:      viper.GetBool("PASSWORD_REQUIRE_LOWER"),
+			PASSWORD_REQUIRE_DIGIT:      viper.GetBool("PASSWORD_REQUIRE_DIGIT"),
+			PASSWORD_REQUIRE_SYMBOL:     viper.GetBool("PASSWORD_REQUIRE_SYMBOL"),
+			PASSWORD_DISALLOW_USER_INFO: viper.GetBool("PASSWORD_DISALLOW_USER_INFO"),
+			PASSWORD_BREACHED_LIST_PATH: viper.GetString("PASSWORD_BREACHED_LIST_PATH"),
+		},
+		Notifier: NotifierConfig{
+			NOTIFIER_DRIVER:    viper.GetString("NOTIFIER_DRIVER"),
+			NOTIFIER_FILE_PATH: viper.GetString("NOTIFIER_FILE_PATH"),
+		},
+		OIDC: OIDCConfig{
+			OIDC_PROVIDER_NAME: viper.GetString("OIDC_PROVIDER_NAME"),
+			OIDC_ISSUER_URL:    viper.GetString("OIDC_ISSUER_URL"),
+			OIDC_CLIENT_ID:     viper.GetString("OIDC_CLIENT_ID"),
+			OIDC_CLIENT_SECRET: viper.GetString("OIDC_CLIENT_SECRET"),
+			OIDC_REDIRECT_URL:  viper.GetString("OIDC_REDIRECT_URL"),
+			OIDC_SCOPES:        viper.GetString("OIDC_SCOPES"),
+			OIDC_STATE_KEY:     viper.GetString("OIDC_STATE_KEY"),
+		},
+		I18n: I18nConfig{
+			I18N_LOCALES_DIR: viper.GetString("I18N_LOCALES_DIR"),
 		},
 	}
 
 	return &config
 }
 
+// Validate refuses to run outside development with secrets left empty or at their
+// placeholder defaults
+func (c *Config) Validate() error {
+	if c.App.APP_ENV == "development" {
+		return nil
+	}
+
+	secrets := []struct {
+		name        string
+		value       string
+		placeholder string
+		required    bool
+	}{
+		{name: "JWT_SECRET", value: c.JWT.JWT_SECRET, placeholder: defaultJWTSecret, required: true},
+		{name: "TOTP_ENCRYPTION_KEY", value: c.Auth.TOTP_ENCRYPTION_KEY, placeholder: defaultTOTPEncryptionKey, required: true},
+		// The state key is only used by OIDC logins
+		{name: "OIDC_STATE_KEY", value: c.OIDC.OIDC_STATE_KEY, placeholder: defaultOIDCStateKey, required: c.OIDC.OIDC_ISSUER_URL != ""},
+	}
+	for _, secret := range secrets {
+		if secret.required && (secret.value == "" || secret.value == secret.placeholder) {
+			return fmt.Errorf("%s must be set to a secret value when APP_ENV is %q", secret.name, c.App.APP_ENV)
+		}
+	}
+	return nil
+}
+
 func setDefaults() {
 	// App defaults
 	viper.SetDefault("APP_ENV", "development")
@@ -105,11 +252,51 @@ func setDefaults() {
 	viper.SetDefault("DB_MAX_IDLE_CONNS", 10)
 	viper.SetDefault("DB_MAX_OPEN_CONNS", 100)
 	viper.SetDefault("DB_CONN_MAX_LIFETIME", "1h")
+	viper.SetDefault("DB_QUERY_TIMEOUT", "5s")
 	viper.SetDefault("LOG_LEVEL", "debug")
 	viper.SetDefault("LOG_FORMAT", "json")
-	viper.SetDefault("JWT_SECRET", "your-secret-key")
+	viper.SetDefault("JWT_SECRET", defaultJWTSecret)
 	viper.SetDefault("JWT_EXPIRE_HOURS", 24)
+	viper.SetDefault("JWT_REFRESH_EXPIRE_HOURS", 720)
+	viper.SetDefault("PASSWORD_RESET_EXPIRE_MINUTES", 30)
+	viper.SetDefault("PASSWORD_RESET_URL", "http://localhost:3000/reset-password")
+	viper.SetDefault("EMAIL_VERIFICATION_EXPIRE_HOURS", 48)
+	viper.SetDefault("EMAIL_VERIFICATION_URL", "http://localhost:3000/verify-email")
+	viper.SetDefault("REQUIRE_VERIFIED_EMAIL", false)
+	viper.SetDefault("TOTP_ISSUER", "echto")
+	viper.SetDefault("TOTP_ENCRYPTION_KEY", defaultTOTPEncryptionKey)
+	viper.SetDefault("LOGIN_MAX_FAILURES", 5)
+	viper.SetDefault("LOGIN_MAX_FAILURES_PER_IP", 20)
+	viper.SetDefault("LOGIN_LOCKOUT_MINUTES", 15)
+	viper.SetDefault("LOGIN_BACKOFF_BASE_SECONDS", 1)
+	viper.SetDefault("LOGIN_BACKOFF_MAX_SECONDS", 60)
+	viper.SetDefault("IMPERSONATION_EXPIRE_MINUTES", 15)
+	viper.SetDefault("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	viper.SetDefault("PASSWORD_BCRYPT_COST", 10)
+	viper.SetDefault("PASSWORD_ARGON2_MEMORY_KB", 65536)
+	viper.SetDefault("PASSWORD_ARGON2_ITERATIONS", 3)
+	viper.SetDefault("PASSWORD_ARGON2_PARALLELISM", 2)
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	viper.SetDefault("PASSWORD_MAX_LENGTH", 72)
+	viper.SetDefault("PASSWORD_REQUIRE_UPPER", false)
+	viper.SetDefault("PASSWORD_REQUIRE_LOWER", false)
+	viper.SetDefault("PASSWORD_REQUIRE_DIGIT", false)
+	viper.SetDefault("PASSWORD_REQUIRE_SYMBOL", false)
+	viper.SetDefault("PASSWORD_DISALLOW_USER_INFO", true)
+	viper.SetDefault("PASSWORD_BREACHED_LIST_PATH", "")
+	viper.SetDefault("NOTIFIER_DRIVER", "log")
+	viper.SetDefault("NOTIFIER_FILE_PATH", "tmp/notifications.log")
+	viper.SetDefault("OIDC_PROVIDER_NAME", "sso")
+	viper.SetDefault("OIDC_ISSUER_URL", "")
+	viper.SetDefault("OIDC_CLIENT_ID", "")
+	viper.SetDefault("OIDC_CLIENT_SECRET", "")
+	viper.SetDefault("OIDC_REDIRECT_URL", "http://localhost:9090/api/v1/auth/oidc/sso/callback")
+	viper.SetDefault("OIDC_SCOPES", "openid email profile")
+	viper.SetDefault("OIDC_STATE_KEY", defaultOIDCStateKey)
+	viper.SetDefault("I18N_LOCALES_DIR", "locales")
 	viper.SetDefault("APP_NAME", "echto")
 	viper.SetDefault("APP_PORT", 9090)
 	viper.SetDefault("APP_HOST", "localhost")
+	viper.SetDefault("REQUIRE_IF_MATCH", false)
+	viper.SetDefault("TRUSTED_PROXIES", "")
 }
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         string
+		stateKey    string
+		issuerURL   string
+		expectedErr bool
+	}{
+		{name: "placeholders in development", env: "development", stateKey: defaultOIDCStateKey, expectedErr: false},
+		{name: "oidc disabled", env: "production", stateKey: defaultOIDCStateKey, expectedErr: false},
+		{name: "oidc enabled with placeholder state key", env: "production", stateKey: defaultOIDCStateKey, issuerURL: "https://accounts.example.com", expectedErr: true},
+		{name: "oidc enabled without state key", env: "production", stateKey: "", issuerURL: "https://accounts.example.com", expectedErr: true},
+		{name: "oidc enabled with state key", env: "production", stateKey: "state-secret", issuerURL: "https://accounts.example.com", expectedErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				App:  AppConfig{APP_ENV: tt.env},
+				JWT:  JWTConfig{JWT_SECRET: "jwt-secret"},
+				Auth: AuthConfig{TOTP_ENCRYPTION_KEY: "totp-secret"},
+				OIDC: OIDCConfig{OIDC_ISSUER_URL: tt.issuerURL, OIDC_STATE_KEY: tt.stateKey},
+			}
+			if tt.env == "development" {
+				cfg.JWT.JWT_SECRET = defaultJWTSecret
+				cfg.Auth.TOTP_ENCRYPTION_KEY = defaultTOTPEncryptionKey
+			}
+
+			err := cfg.Validate()
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_RequiresSecretsOutsideDevelopment(t *testing.T) {
+	cfg := &Config{
+		App:  AppConfig{APP_ENV: "production"},
+		JWT:  JWTConfig{JWT_SECRET: defaultJWTSecret},
+		Auth: AuthConfig{TOTP_ENCRYPTION_KEY: "totp-secret"},
+	}
+
+	assert.ErrorContains(t, cfg.Validate(), "JWT_SECRET")
+}
@@ -14,6 +14,15 @@ func AutoMigrate(db *gorm.DB) error {
 	// Auto migrate all entities
 	if err := db.AutoMigrate(
 		&entity.User{},
+		&entity.RefreshToken{},
+		&entity.RevokedToken{},
+		&entity.PasswordResetToken{},
+		&entity.EmailVerificationToken{},
+		&entity.RecoveryCode{},
+		&entity.LoginFailure{},
+		&entity.APIKey{},
+		&entity.LinkedIdentity{},
+		&entity.Session{},
 	); err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to run auto migration")
 		return err
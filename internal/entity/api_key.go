@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+const (
+	// ScopeUsersRead allows an API key to read users
+	ScopeUsersRead = "users:read"
+	// ScopeUsersWrite allows an API key to update and delete users
+	ScopeUsersWrite = "users:write"
+)
+
+// APIKey is a long-lived credential for non-interactive clients acting as its owner.
+// Only the SHA-256 hash of the key is stored, next to a short prefix to recognize it by.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json;type:text;not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// LinkedIdentity ties an account at an external OpenID Connect provider to a user
+type LinkedIdentity struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Provider    string     `json:"provider" gorm:"not null;uniqueIndex:idx_linked_identities_provider_subject"`
+	Subject     string     `json:"subject" gorm:"not null;uniqueIndex:idx_linked_identities_provider_subject"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (LinkedIdentity) TableName() string {
+	return "linked_identities"
+}
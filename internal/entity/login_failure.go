@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// LoginFailure counts consecutive failed logins for a throttle key, which is
+// either an account ("email:<address>") or a client IP ("ip:<address>")
+type LoginFailure struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Key          string     `json:"key" gorm:"uniqueIndex;not null"`
+	Failures     int        `json:"failures" gorm:"not null;default:0"`
+	LastFailedAt time.Time  `json:"last_failed_at" gorm:"not null"`
+	LockedUntil  *time.Time `json:"locked_until"`
+}
+
+func (LoginFailure) TableName() string {
+	return "login_failures"
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// RevokedToken is a denylist entry for access tokens. Entries with a JTI revoke a
+// single token; entries without one revoke every token issued to UserID up to
+// RevokedAt. Rows are only relevant until ExpiresAt, after which the tokens they
+// cover have expired on their own.
+type RevokedToken struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	JTI       *string   `json:"jti" gorm:"column:jti;uniqueIndex"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	RevokedAt time.Time `json:"revoked_at" gorm:"not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// Session is a device signed in to an account. It lives as long as the refresh
+// token family started at login, and FamilyID doubles as the "sid" claim of the
+// access tokens issued in it.
+type Session struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	FamilyID   string    `json:"-" gorm:"uniqueIndex;not null"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
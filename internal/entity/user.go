@@ -6,11 +6,23 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"not null"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"`
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	Name            string     `json:"name" gorm:"not null"`
+	Email           string     `json:"email" gorm:"uniqueIndex;not null"`
+	Password        string     `json:"-" gorm:"not null"`
+	Role            string     `json:"role" gorm:"not null;default:user"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPSecret      *string    `json:"-" gorm:"column:totp_secret"`
+	TOTPEnabledAt   *time.Time `json:"-" gorm:"column:totp_enabled_at"`
+	TOTPLastStep    int64      `json:"-" gorm:"column:totp_last_step;not null;default:0"`
+	// Version is incremented by every update, so that concurrent writes can be detected
+	Version   uint           `json:"-" gorm:"not null;default:1"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"echto/internal/model"
+	"echto/internal/service"
+	"echto/pkg/problem"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey handles POST /api/v1/users/:id/api-keys
+// @Summary Create API key
+// @Description Create an API key acting as the authenticated user with the given scopes. The key is only returned once; send it as "Authorization: Bearer <key>".
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param apiKey body model.APIKeyCreateRequest true "API key data"
+// @Success 201 {object} model.APIKeyCreateResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	// Parse user ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	var req model.APIKeyCreateRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Create API key
+	apiKey, err := h.apiKeyService.CreateAPIKey(c.Request().Context(), uint(id), &req)
+	if err != nil {
+		return serviceError(c, err, "Failed to create API key")
+	}
+
+	return c.JSON(http.StatusCreated, apiKey)
+}
+
+// GetAPIKeys handles GET /api/v1/users/:id/api-keys
+// @Summary List API keys
+// @Description List the active API keys of a user without their secrets. Users may only list their own keys unless they are an admin.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.APIKeyListResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(c echo.Context) error {
+	// Parse user ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	// Get API keys
+	apiKeys, err := h.apiKeyService.GetAPIKeys(c.Request().Context(), uint(id))
+	if err != nil {
+		return serviceError(c, err, "Failed to get API keys")
+	}
+
+	return c.JSON(http.StatusOK, apiKeys)
+}
+
+// RevokeAPIKey handles DELETE /api/v1/users/:id/api-keys/:keyId
+// @Summary Revoke API key
+// @Description Revoke an API key so it can no longer be used. Users may only revoke their own keys unless they are an admin.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param keyId path int true "API key ID"
+// @Success 204 "API key revoked successfully"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/api-keys/{keyId} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c echo.Context) error {
+	// Parse user ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	// Parse API key ID
+	keyID, err := strconv.ParseUint(c.Param("keyId"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid API key ID")
+	}
+
+	// Revoke API key
+	if err := h.apiKeyService.RevokeAPIKey(c.Request().Context(), uint(id), uint(keyID)); err != nil {
+		return serviceError(c, err, "Failed to revoke API key")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"echto/internal/model"
+	"echto/internal/service"
+	"echto/pkg/token"
+	"echto/pkg/validation"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAPIKeyService is a mock implementation of APIKeyService
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, userID uint, req *model.APIKeyCreateRequest) (*model.APIKeyCreateResponse, error) {
+	args := m.Called(userID, req)
+	return args.Get(0).(*model.APIKeyCreateResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) GetAPIKeys(ctx context.Context, userID uint) (*model.APIKeyListResponse, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*model.APIKeyListResponse), args.Error(1)
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, userID, keyID uint) error {
+	args := m.Called(userID, keyID)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyService) Authenticate(ctx context.Context, rawKey string) (*token.Claims, bool, error) {
+	args := m.Called(rawKey)
+	return args.Get(0).(*token.Claims), args.Bool(1), args.Error(2)
+}
+
+func TestAPIKeyHandler_CreateAPIKey(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		requestBody    model.APIKeyCreateRequest
+		mockSetup      func(*MockAPIKeyService)
+		expectedStatus int
+	}{
+		{
+			name: "successful creation",
+			requestBody: model.APIKeyCreateRequest{
+				Name:   "nightly sync",
+				Scopes: []string{"users:read"},
+			},
+			mockSetup: func(mockService *MockAPIKeyService) {
+				mockService.On("CreateAPIKey", uint(1), mock.AnythingOfType("*model.APIKeyCreateRequest")).
+					Return(&model.APIKeyCreateResponse{
+						APIKeyResponse: model.APIKeyResponse{ID: 1, Name: "nightly sync", Prefix: "echto_abcdefgh"},
+						Key:            "echto_abcdefgh-rest",
+					}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "unknown scope",
+			requestBody: model.APIKeyCreateRequest{
+				Name:   "nightly sync",
+				Scopes: []string{"users:admin"},
+			},
+			mockSetup:      func(mockService *MockAPIKeyService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "missing scopes",
+			requestBody: model.APIKeyCreateRequest{
+				Name: "nightly sync",
+			},
+			mockSetup:      func(mockService *MockAPIKeyService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAPIKeyService)
+			tt.mockSetup(mockService)
+
+			handler := NewAPIKeyHandler(mockService)
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users/1/api-keys", bytes.NewReader(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/users/:id/api-keys")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			run(c, handler.CreateAPIKey)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAPIKeyHandler_RevokeAPIKey(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		keyID          string
+		mockSetup      func(*MockAPIKeyService)
+		expectedStatus int
+	}{
+		{
+			name:  "successful revocation",
+			keyID: "3",
+			mockSetup: func(mockService *MockAPIKeyService) {
+				mockService.On("RevokeAPIKey", uint(1), uint(3)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:  "key of another user",
+			keyID: "4",
+			mockSetup: func(mockService *MockAPIKeyService) {
+				mockService.On("RevokeAPIKey", uint(1), uint(4)).Return(service.ErrAPIKeyNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAPIKeyService)
+			tt.mockSetup(mockService)
+
+			handler := NewAPIKeyHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/1/api-keys/"+tt.keyID, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/users/:id/api-keys/:keyId")
+			c.SetParamNames("id", "keyId")
+			c.SetParamValues("1", tt.keyID)
+
+			run(c, handler.RevokeAPIKey)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"echto/internal/model"
+	"echto/internal/service"
+	echtoMiddleware "echto/pkg/middleware"
+	"echto/pkg/problem"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type AuthHandler struct {
+	authService service.AuthService
+}
+
+func NewAuthHandler(authService service.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+	}
+}
+
+// Login handles POST /api/v1/auth/login
+// @Summary Login
+// @Description Authenticate with email and password and receive an access token. When two-factor authentication is enabled, the response carries a challenge token to complete at /api/v1/auth/2fa/verify instead.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body model.LoginRequest true "Login credentials"
+// @Success 200 {object} model.LoginResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 429 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /api/v1/auth/login [post]
+func (h *AuthHandler) Login(c echo.Context) error {
+	var req model.LoginRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Authenticate
+	tokens, err := h.authService.Login(c.Request().Context(), &req, clientInfo(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to login")
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// VerifyTwoFactor handles POST /api/v1/auth/2fa/verify
+// @Summary Complete two-factor login
+// @Description Exchange a login challenge token and a TOTP or recovery code for an access and refresh token pair
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorVerifyRequest true "Challenge token and code"
+// @Success 200 {object} model.TokenResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 429 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /api/v1/auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactor(c echo.Context) error {
+	var req model.TwoFactorVerifyRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Verify second factor
+	tokens, err := h.authService.VerifyTwoFactor(c.Request().Context(), &req, clientInfo(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to login")
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh handles POST /api/v1/auth/refresh
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token pair. Each refresh token can be used once; replaying a used token revokes every token issued from the same login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token body model.RefreshRequest true "Refresh token"
+// @Success 200 {object} model.TokenResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /api/v1/auth/refresh [post]
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	var req model.RefreshRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Rotate refresh token
+	tokens, err := h.authService.Refresh(c.Request().Context(), &req, clientInfo(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to refresh token")
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// Logout handles POST /api/v1/auth/logout
+// @Summary Logout
+// @Description Revoke the current access token and end its session and refresh token family. Tokens without a session end the family of the refresh token, when provided.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param token body model.LogoutRequest false "Refresh token to revoke, for tokens without a session"
+// @Success 204 "Logged out successfully"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/auth/logout [post]
+func (h *AuthHandler) Logout(c echo.Context) error {
+	claims, ok := echtoMiddleware.ClaimsFromContext(c)
+	if !ok {
+		return problem.New(http.StatusUnauthorized, "unauthorized", "Authentication required")
+	}
+
+	var req model.LogoutRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Revoke tokens
+	if err := h.authService.Logout(c.Request().Context(), claims, &req); err != nil {
+		return serviceError(c, err, "Failed to logout")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RevokeUserSessions handles DELETE /api/v1/users/:id/sessions
+// @Summary Revoke all sessions of a user
+// @Description Revoke every access and refresh token issued to a user. Users may only revoke their own sessions unless they are an admin.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 204 "Sessions revoked successfully"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/sessions [delete]
+func (h *AuthHandler) RevokeUserSessions(c echo.Context) error {
+	// Parse user ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	// Revoke sessions
+	if err := h.authService.RevokeUserSessions(c.Request().Context(), uint(id)); err != nil {
+		return serviceError(c, err, "Failed to revoke sessions")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UnlockUser handles POST /api/v1/users/:id/unlock
+// @Summary Unlock user
+// @Description Clear the failed login counter of a user, lifting any lockout or backoff on the account. Requires the admin role.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 204 "User unlocked successfully"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/unlock [post]
+func (h *AuthHandler) UnlockUser(c echo.Context) error {
+	// Parse user ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	// Unlock user
+	if err := h.authService.UnlockUser(c.Request().Context(), uint(id)); err != nil {
+		return serviceError(c, err, "Failed to unlock user")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Impersonate handles POST /api/v1/users/:id/impersonate
+// @Summary Impersonate user
+// @Description Issue a short-lived access token for acting as another user. The token carries the admin as the actor, requests made with it are marked in the request log, and password changes, account deletion and credential management are refused. No refresh token is issued. Requires the admin role.
+// @Tags Auth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.ImpersonationResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/impersonate [post]
+func (h *AuthHandler) Impersonate(c echo.Context) error {
+	actorID, ok := echtoMiddleware.UserIDFromContext(c)
+	if !ok {
+		return problem.New(http.StatusUnauthorized, "unauthorized", "Authentication required")
+	}
+
+	// Parse user ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	// Impersonate user
+	impersonation, err := h.authService.Impersonate(c.Request().Context(), actorID, uint(id))
+	if err != nil {
+		return serviceError(c, err, "Failed to impersonate user")
+	}
+
+	return c.JSON(http.StatusOK, impersonation)
+}
+
+// ChangePassword handles PUT /api/v1/users/:id/password
+// @Summary Change password
+// @Description Change the password of the authenticated user. Every existing session, including the current one, is signed out. Wrong current passwords count as failed logins of the account.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param password body model.PasswordChangeRequest true "Current and new password"
+// @Success 204 "Password changed successfully"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 429 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/password [put]
+func (h *AuthHandler) ChangePassword(c echo.Context) error {
+	// Parse user ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	var req model.PasswordChangeRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Change password
+	if err := h.authService.ChangePassword(c.Request().Context(), uint(id), &req); err != nil {
+		return serviceError(c, err, "Failed to change password")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// loginThrottledError returns a 429 problem and tells the client when it may try again
+func loginThrottledError(c echo.Context, throttled *service.LoginThrottledError) error {
+	retryAfter := int64(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.FormatInt(retryAfter, 10))
+
+	if throttled.Locked {
+		return problem.New(http.StatusTooManyRequests, "account_locked", "Too many failed login attempts, try again later")
+	}
+	return problem.New(http.StatusTooManyRequests, "too_many_attempts", "Too many login attempts, slow down")
+}
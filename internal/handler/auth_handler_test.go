@@ -0,0 +1,580 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/internal/service"
+	echtoMiddleware "echto/pkg/middleware"
+	"echto/pkg/token"
+	"echto/pkg/validation"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuthService is a mock implementation of AuthService
+type MockAuthService struct {
+	mock.Mock
+}
+
+func (m *MockAuthService) Login(ctx context.Context, req *model.LoginRequest, client service.ClientInfo) (*model.LoginResponse, error) {
+	args := m.Called(req, client)
+	return args.Get(0).(*model.LoginResponse), args.Error(1)
+}
+
+func (m *MockAuthService) LoginUser(ctx context.Context, user *entity.User, client service.ClientInfo) (*model.LoginResponse, error) {
+	args := m.Called(user, client)
+	return args.Get(0).(*model.LoginResponse), args.Error(1)
+}
+
+func (m *MockAuthService) VerifyTwoFactor(ctx context.Context, req *model.TwoFactorVerifyRequest, client service.ClientInfo) (*model.TokenResponse, error) {
+	args := m.Called(req, client)
+	return args.Get(0).(*model.TokenResponse), args.Error(1)
+}
+
+func (m *MockAuthService) Refresh(ctx context.Context, req *model.RefreshRequest, client service.ClientInfo) (*model.TokenResponse, error) {
+	args := m.Called(req, client)
+	return args.Get(0).(*model.TokenResponse), args.Error(1)
+}
+
+func (m *MockAuthService) Logout(ctx context.Context, claims *token.Claims, req *model.LogoutRequest) error {
+	args := m.Called(claims, req)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeUserSessions(ctx context.Context, userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeAllTokens(ctx context.Context, userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) UnlockUser(ctx context.Context, userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) Impersonate(ctx context.Context, actorID, userID uint) (*model.ImpersonationResponse, error) {
+	args := m.Called(actorID, userID)
+	return args.Get(0).(*model.ImpersonationResponse), args.Error(1)
+}
+
+func (m *MockAuthService) ChangePassword(ctx context.Context, userID uint, req *model.PasswordChangeRequest) error {
+	args := m.Called(userID, req)
+	return args.Error(0)
+}
+
+func TestAuthHandler_Login(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		requestBody    model.LoginRequest
+		mockSetup      func(*MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name: "successful login",
+			requestBody: model.LoginRequest{
+				Email:    "john@example.com",
+				Password: "password123",
+			},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Login", mock.AnythingOfType("*model.LoginRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return(&model.LoginResponse{
+						TokenResponse: &model.TokenResponse{
+							AccessToken: "token",
+							TokenType:   "Bearer",
+							ExpiresIn:   3600,
+						},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "two factor challenge",
+			requestBody: model.LoginRequest{
+				Email:    "admin@example.com",
+				Password: "password123",
+			},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Login", mock.AnythingOfType("*model.LoginRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return(&model.LoginResponse{
+						TwoFactorRequired: true,
+						ChallengeToken:    "challenge",
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "invalid credentials",
+			requestBody: model.LoginRequest{
+				Email:    "john@example.com",
+				Password: "wrong-password",
+			},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Login", mock.AnythingOfType("*model.LoginRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return((*model.LoginResponse)(nil), service.ErrInvalidCredentials)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "unverified email",
+			requestBody: model.LoginRequest{
+				Email:    "john@example.com",
+				Password: "password123",
+			},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Login", mock.AnythingOfType("*model.LoginRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return((*model.LoginResponse)(nil), service.ErrEmailNotVerified)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "backing off after failures",
+			requestBody: model.LoginRequest{
+				Email:    "john@example.com",
+				Password: "password123",
+			},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Login", mock.AnythingOfType("*model.LoginRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return((*model.LoginResponse)(nil), &service.LoginThrottledError{RetryAfter: 1500 * time.Millisecond})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "account locked",
+			requestBody: model.LoginRequest{
+				Email:    "john@example.com",
+				Password: "password123",
+			},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Login", mock.AnythingOfType("*model.LoginRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return((*model.LoginResponse)(nil), &service.LoginThrottledError{RetryAfter: 15 * time.Minute, Locked: true})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "missing password",
+			requestBody: model.LoginRequest{
+				Email: "john@example.com",
+			},
+			mockSetup:      func(mockService *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAuthService)
+			tt.mockSetup(mockService)
+
+			handler := NewAuthHandler(mockService)
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler.Login)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedStatus == http.StatusTooManyRequests {
+				assert.NotEmpty(t, rec.Header().Get(echo.HeaderRetryAfter))
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthHandler_Login_ClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies string
+		remoteAddr     string
+		forwardedFor   string
+		expectedIP     string
+	}{
+		{
+			name:         "no trusted proxies ignores a spoofed header",
+			remoteAddr:   "203.0.113.7:51234",
+			forwardedFor: "198.51.100.1",
+			expectedIP:   "203.0.113.7",
+		},
+		{
+			name:           "trusted proxy forwards the client",
+			trustedProxies: "10.0.0.0/8",
+			remoteAddr:     "10.1.2.3:51234",
+			forwardedFor:   "198.51.100.1",
+			expectedIP:     "198.51.100.1",
+		},
+		{
+			name:           "header prepended by the client is skipped",
+			trustedProxies: "10.0.0.0/8",
+			remoteAddr:     "10.1.2.3:51234",
+			forwardedFor:   "192.0.2.9, 198.51.100.1",
+			expectedIP:     "198.51.100.1",
+		},
+		{
+			name:           "untrusted proxy is the client",
+			trustedProxies: "10.0.0.0/8",
+			remoteAddr:     "203.0.113.7:51234",
+			forwardedFor:   "198.51.100.1",
+			expectedIP:     "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Validator = validation.New()
+			extractor, err := NewIPExtractor(tt.trustedProxies)
+			assert.NoError(t, err)
+			e.IPExtractor = extractor
+
+			mockService := new(MockAuthService)
+			mockService.On("Login", mock.AnythingOfType("*model.LoginRequest"), mock.MatchedBy(func(client service.ClientInfo) bool {
+				return client.IPAddress == tt.expectedIP
+			})).Return(&model.LoginResponse{TokenResponse: &model.TokenResponse{AccessToken: "token", TokenType: "Bearer"}}, nil)
+
+			handler := NewAuthHandler(mockService)
+
+			reqBody, _ := json.Marshal(model.LoginRequest{Email: "john@example.com", Password: "password123"})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderXForwardedFor, tt.forwardedFor)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler.Login)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNewIPExtractor_InvalidProxy(t *testing.T) {
+	_, err := NewIPExtractor("10.0.0.0/8, not-a-cidr")
+	assert.Error(t, err)
+}
+
+func TestAuthHandler_VerifyTwoFactor(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		requestBody    model.TwoFactorVerifyRequest
+		mockSetup      func(*MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name:        "valid code",
+			requestBody: model.TwoFactorVerifyRequest{ChallengeToken: "challenge", Code: "123456"},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("VerifyTwoFactor", mock.AnythingOfType("*model.TwoFactorVerifyRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return(&model.TokenResponse{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "invalid code",
+			requestBody: model.TwoFactorVerifyRequest{ChallengeToken: "challenge", Code: "000000"},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("VerifyTwoFactor", mock.AnythingOfType("*model.TwoFactorVerifyRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return((*model.TokenResponse)(nil), service.ErrInvalidTwoFactorCode)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAuthService)
+			tt.mockSetup(mockService)
+
+			handler := NewAuthHandler(mockService)
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/2fa/verify", bytes.NewReader(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler.VerifyTwoFactor)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthHandler_Refresh(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		requestBody    model.RefreshRequest
+		mockSetup      func(*MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name:        "successful refresh",
+			requestBody: model.RefreshRequest{RefreshToken: "valid"},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Refresh", mock.AnythingOfType("*model.RefreshRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return(&model.TokenResponse{
+						AccessToken:  "access",
+						RefreshToken: "rotated",
+						TokenType:    "Bearer",
+						ExpiresIn:    3600,
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "reused refresh token",
+			requestBody: model.RefreshRequest{RefreshToken: "already-used"},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Refresh", mock.AnythingOfType("*model.RefreshRequest"), mock.AnythingOfType("service.ClientInfo")).
+					Return((*model.TokenResponse)(nil), service.ErrRefreshTokenReused)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing refresh token",
+			requestBody:    model.RefreshRequest{},
+			mockSetup:      func(mockService *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAuthService)
+			tt.mockSetup(mockService)
+
+			handler := NewAuthHandler(mockService)
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewReader(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler.Refresh)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthHandler_Logout(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	t.Run("successful logout", func(t *testing.T) {
+		mockService := new(MockAuthService)
+		claims := &token.Claims{UserID: 1}
+		mockService.On("Logout", claims, mock.AnythingOfType("*model.LogoutRequest")).Return(nil)
+
+		handler := NewAuthHandler(mockService)
+
+		reqBody, _ := json.Marshal(model.LogoutRequest{RefreshToken: "refresh"})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", bytes.NewReader(reqBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(echtoMiddleware.ContextKeyClaims, claims)
+
+		run(c, handler.Logout)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing claims", func(t *testing.T) {
+		mockService := new(MockAuthService)
+		handler := NewAuthHandler(mockService)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		run(c, handler.Logout)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestAuthHandler_ChangePassword(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		requestBody    model.PasswordChangeRequest
+		mockSetup      func(*MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name: "successful password change",
+			requestBody: model.PasswordChangeRequest{
+				CurrentPassword: "password123",
+				NewPassword:     "new-password123",
+			},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("ChangePassword", uint(1), mock.AnythingOfType("*model.PasswordChangeRequest")).
+					Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "wrong current password",
+			requestBody: model.PasswordChangeRequest{
+				CurrentPassword: "wrong-password",
+				NewPassword:     "new-password123",
+			},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("ChangePassword", uint(1), mock.AnythingOfType("*model.PasswordChangeRequest")).
+					Return(service.ErrInvalidCurrentPassword)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "locked after wrong current passwords",
+			requestBody: model.PasswordChangeRequest{
+				CurrentPassword: "password123",
+				NewPassword:     "new-password123",
+			},
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("ChangePassword", uint(1), mock.AnythingOfType("*model.PasswordChangeRequest")).
+					Return(&service.LoginThrottledError{RetryAfter: 15 * time.Minute, Locked: true})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "new password equals current password",
+			requestBody: model.PasswordChangeRequest{
+				CurrentPassword: "password123",
+				NewPassword:     "password123",
+			},
+			mockSetup:      func(mockService *MockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAuthService)
+			tt.mockSetup(mockService)
+
+			handler := NewAuthHandler(mockService)
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/users/1/password", bytes.NewReader(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/users/:id/password")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			run(c, handler.ChangePassword)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthHandler_Impersonate(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		userID         string
+		mockSetup      func(*MockAuthService)
+		expectedStatus int
+	}{
+		{
+			name:   "successful impersonation",
+			userID: "2",
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Impersonate", uint(1), uint(2)).Return(&model.ImpersonationResponse{
+					AccessToken: "token",
+					TokenType:   "Bearer",
+					ExpiresIn:   900,
+					ActorID:     1,
+					SubjectID:   2,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "user not found",
+			userID: "99",
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Impersonate", uint(1), uint(99)).Return((*model.ImpersonationResponse)(nil), service.ErrUserNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "impersonating self",
+			userID: "1",
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Impersonate", uint(1), uint(1)).Return((*model.ImpersonationResponse)(nil), service.ErrCannotImpersonateSelf)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "impersonating another admin",
+			userID: "3",
+			mockSetup: func(mockService *MockAuthService) {
+				mockService.On("Impersonate", uint(1), uint(3)).Return((*model.ImpersonationResponse)(nil), service.ErrCannotImpersonateAdmin)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockAuthService)
+			tt.mockSetup(mockService)
+
+			handler := NewAuthHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users/"+tt.userID+"/impersonate", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/users/:id/impersonate")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.userID)
+			c.Set(echtoMiddleware.ContextKeyUserID, uint(1))
+
+			run(c, handler.Impersonate)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
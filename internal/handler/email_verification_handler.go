@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"echto/internal/model"
+	"echto/internal/service"
+	"echto/pkg/problem"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type EmailVerificationHandler struct {
+	emailVerificationService service.EmailVerificationService
+}
+
+func NewEmailVerificationHandler(emailVerificationService service.EmailVerificationService) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		emailVerificationService: emailVerificationService,
+	}
+}
+
+// ConfirmEmail handles POST /api/v1/auth/verify-email
+// @Summary Verify email
+// @Description Mark the user's email address as verified using the token sent on registration or email change
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.EmailVerificationConfirmRequest true "Verification token"
+// @Success 204 "Email verified successfully"
+// @Failure 400 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /api/v1/auth/verify-email [post]
+func (h *EmailVerificationHandler) ConfirmEmail(c echo.Context) error {
+	var req model.EmailVerificationConfirmRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Verify email
+	if err := h.emailVerificationService.ConfirmEmail(c.Request().Context(), &req); err != nil {
+		return serviceError(c, err, "Failed to verify email")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ResendVerification handles POST /api/v1/auth/verify-email/resend
+// @Summary Resend verification email
+// @Description Send a new verification link to an unverified account. The response is the same whether or not an account exists for the email.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.EmailVerificationResendRequest true "Account email"
+// @Success 202 {object} model.SuccessResponse
+// @Failure 400 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /api/v1/auth/verify-email/resend [post]
+func (h *EmailVerificationHandler) ResendVerification(c echo.Context) error {
+	var req model.EmailVerificationResendRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Resend verification
+	if err := h.emailVerificationService.ResendVerification(c.Request().Context(), &req); err != nil {
+		return serviceError(c, err, "Failed to resend verification")
+	}
+
+	return c.JSON(http.StatusAccepted, model.SuccessResponse{
+		Message: "If an unverified account exists for this email, a verification link has been sent",
+		Code:    http.StatusAccepted,
+	})
+}
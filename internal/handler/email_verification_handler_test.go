@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/internal/service"
+	"echto/pkg/validation"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockEmailVerificationService is a mock implementation of EmailVerificationService
+type MockEmailVerificationService struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationService) SendVerification(ctx context.Context, user *entity.User) {
+	m.Called(user)
+}
+
+func (m *MockEmailVerificationService) ResendVerification(ctx context.Context, req *model.EmailVerificationResendRequest) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) ConfirmEmail(ctx context.Context, req *model.EmailVerificationConfirmRequest) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+func TestEmailVerificationHandler_ConfirmEmail(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		requestBody    model.EmailVerificationConfirmRequest
+		mockSetup      func(*MockEmailVerificationService)
+		expectedStatus int
+	}{
+		{
+			name:        "successful verification",
+			requestBody: model.EmailVerificationConfirmRequest{Token: "valid"},
+			mockSetup: func(mockService *MockEmailVerificationService) {
+				mockService.On("ConfirmEmail", mock.AnythingOfType("*model.EmailVerificationConfirmRequest")).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:        "stale token",
+			requestBody: model.EmailVerificationConfirmRequest{Token: "stale"},
+			mockSetup: func(mockService *MockEmailVerificationService) {
+				mockService.On("ConfirmEmail", mock.AnythingOfType("*model.EmailVerificationConfirmRequest")).
+					Return(service.ErrInvalidVerificationToken)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing token",
+			requestBody:    model.EmailVerificationConfirmRequest{},
+			mockSetup:      func(mockService *MockEmailVerificationService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockEmailVerificationService)
+			tt.mockSetup(mockService)
+
+			handler := NewEmailVerificationHandler(mockService)
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/verify-email", bytes.NewReader(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler.ConfirmEmail)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"echto/internal/service"
+	"echto/pkg/problem"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// errorMapping is the problem reported for a domain error
+type errorMapping struct {
+	status  int
+	code    string
+	message string
+}
+
+// domainErrors maps every domain error of the service layer to its HTTP response.
+// New domain errors only need an entry here.
+var domainErrors = map[error]errorMapping{
+	service.ErrUserNotFound:     {http.StatusNotFound, "user_not_found", "User not found"},
+	service.ErrEmailExists:      {http.StatusConflict, "email_exists", "Email already exists"},
+	service.ErrEmailNotVerified: {http.StatusForbidden, "email_not_verified", "Email address has not been verified"},
+	service.ErrInvalidSort:      {http.StatusBadRequest, "invalid_sort", "Sort by id, name, email, role, created_at or updated_at, optionally followed by :asc or :desc"},
+	service.ErrInvalidDateRange: {http.StatusBadRequest, "invalid_date_range", "created_to must be later than created_from"},
+	service.ErrInvalidCursor:    {http.StatusBadRequest, "invalid_cursor", "Cursor is malformed or was issued for a different sort order"},
+	service.ErrVersionMismatch:  {http.StatusPreconditionFailed, "precondition_failed", "The user has been modified since it was read"},
+	service.ErrVersionRequired:  {http.StatusPreconditionRequired, "precondition_required", "Send the ETag of the user in an If-Match header"},
+	service.ErrConcurrentUpdate: {http.StatusConflict, "concurrent_update", "The user was modified by another request at the same time, try again"},
+
+	service.ErrInvalidCredentials:       {http.StatusUnauthorized, "invalid_credentials", "Invalid email or password"},
+	service.ErrInvalidCurrentPassword:   {http.StatusBadRequest, "invalid_current_password", "Current password is incorrect"},
+	service.ErrInvalidChallengeToken:    {http.StatusUnauthorized, "invalid_challenge_token", "Invalid or expired challenge token"},
+	service.ErrInvalidRefreshToken:      {http.StatusUnauthorized, "invalid_refresh_token", "Invalid or expired refresh token"},
+	service.ErrRefreshTokenReused:       {http.StatusUnauthorized, "refresh_token_reused", "Refresh token has already been used"},
+	service.ErrInvalidResetToken:        {http.StatusBadRequest, "invalid_reset_token", "Invalid or expired password reset token"},
+	service.ErrInvalidVerificationToken: {http.StatusBadRequest, "invalid_verification_token", "Invalid or expired verification token"},
+
+	service.ErrTwoFactorAlreadyEnabled: {http.StatusConflict, "two_factor_enabled", "Two-factor authentication is already enabled"},
+	service.ErrTwoFactorNotEnrolled:    {http.StatusBadRequest, "two_factor_not_enrolled", "Start enrollment before confirming a code"},
+	service.ErrInvalidTwoFactorCode:    {http.StatusUnauthorized, "invalid_two_factor_code", "Invalid two-factor code"},
+	service.ErrInvalidConfirmationCode: {http.StatusBadRequest, "invalid_two_factor_code", "Invalid two-factor code"},
+
+	service.ErrAPIKeyNotFound:  {http.StatusNotFound, "api_key_not_found", "API key not found"},
+	service.ErrSessionNotFound: {http.StatusNotFound, "session_not_found", "Session not found"},
+
+	service.ErrCannotImpersonateSelf:  {http.StatusBadRequest, "invalid_target", "You cannot impersonate yourself"},
+	service.ErrCannotImpersonateAdmin: {http.StatusForbidden, "forbidden", "Admins cannot be impersonated"},
+
+	service.ErrOIDCProviderNotFound:  {http.StatusNotFound, "provider_not_found", "Identity provider not found"},
+	service.ErrInvalidOIDCState:      {http.StatusBadRequest, "invalid_state", "Login state is missing, expired or does not match"},
+	service.ErrOIDCLoginFailed:       {http.StatusUnauthorized, "oidc_login_failed", "Login with the identity provider failed"},
+	service.ErrIdentityEmailConflict: {http.StatusConflict, "email_exists", "An account with this email exists, but the account or the provider has not verified the email"},
+}
+
+// serviceError returns the problem for an error returned by a service. Errors that
+// are not domain errors are reported as a 500 with the given message.
+func serviceError(c echo.Context, err error, failureMessage string) error {
+	var throttled *service.LoginThrottledError
+	if errors.As(err, &throttled) {
+		return loginThrottledError(c, throttled)
+	}
+
+	var policyErr *service.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return passwordPolicyError(policyErr)
+	}
+
+	for domainErr, mapping := range domainErrors {
+		if errors.Is(err, domainErr) {
+			return problem.New(mapping.status, mapping.code, mapping.message)
+		}
+	}
+
+	return problem.New(http.StatusInternalServerError, "internal_server_error", failureMessage).WithInternal(err)
+}
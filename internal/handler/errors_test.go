@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"echto/internal/service"
+	"echto/pkg/problem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// run calls a handler and renders any returned error the way the server's error handler does
+func run(c echo.Context, h echo.HandlerFunc) {
+	if err := h(c); err != nil {
+		problem.HTTPErrorHandler(err, c)
+	}
+}
+
+func TestServiceError(t *testing.T) {
+	e := echo.New()
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{name: "domain error", err: service.ErrUserNotFound, expectedStatus: http.StatusNotFound, expectedCode: "user_not_found"},
+		{name: "wrapped domain error", err: fmt.Errorf("update user 7: %w", service.ErrEmailExists), expectedStatus: http.StatusConflict, expectedCode: "email_exists"},
+		{name: "unexpected error", err: errors.New("connection refused"), expectedStatus: http.StatusInternalServerError, expectedCode: "internal_server_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, func(c echo.Context) error {
+				return serviceError(c, tt.err, "Failed to get user")
+			})
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"code":"`+tt.expectedCode+`"`)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"echto/internal/model"
+	"echto/internal/service"
+	"echto/pkg/problem"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// oidcStateCookie holds the sealed login state between the redirect and the callback
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/api/v1/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidcService service.OIDCService
+}
+
+func NewOIDCHandler(oidcService service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+	}
+}
+
+// Login handles GET /api/v1/auth/oidc/:provider/login
+// @Summary Start OpenID Connect login
+// @Description Redirect to the identity provider to log in with the authorization code flow and PKCE
+// @Tags Auth
+// @Param provider path string true "Provider name"
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /api/v1/auth/oidc/{provider}/login [get]
+func (h *OIDCHandler) Login(c echo.Context) error {
+	start, err := h.oidcService.StartLogin(c.Request().Context(), c.Param("provider"))
+	if err != nil {
+		return serviceError(c, err, "Failed to start login")
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    start.State,
+		Path:     oidcCookiePath,
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return c.Redirect(http.StatusFound, start.AuthURL)
+}
+
+// Callback handles GET /api/v1/auth/oidc/:provider/callback
+// @Summary Complete OpenID Connect login
+// @Description Redeem the authorization code returned by the identity provider. The external identity is linked to the user with the same verified email, or a new user is created on first login.
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string false "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} model.LoginResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 409 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /api/v1/auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c echo.Context) error {
+	var req model.OIDCCallbackRequest
+
+	// Bind query parameters
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid callback parameters")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// The login state is single use
+	var sealedState string
+	if cookie, err := c.Cookie(oidcStateCookie); err == nil {
+		sealedState = cookie.Value
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     oidcCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	// Complete login
+	tokens, err := h.oidcService.CompleteLogin(c.Request().Context(), c.Param("provider"), &req, sealedState, clientInfo(c))
+	if err != nil {
+		return serviceError(c, err, "Failed to login")
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
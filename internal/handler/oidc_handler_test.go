@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"echto/internal/model"
+	"echto/internal/service"
+	"echto/pkg/validation"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOIDCService is a mock implementation of OIDCService
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) StartLogin(ctx context.Context, provider string) (*model.OIDCLoginStart, error) {
+	args := m.Called(provider)
+	return args.Get(0).(*model.OIDCLoginStart), args.Error(1)
+}
+
+func (m *MockOIDCService) CompleteLogin(ctx context.Context, provider string, req *model.OIDCCallbackRequest, sealedState string, client service.ClientInfo) (*model.LoginResponse, error) {
+	args := m.Called(provider, req, sealedState, client)
+	return args.Get(0).(*model.LoginResponse), args.Error(1)
+}
+
+func TestOIDCHandler_Login(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	mockService := new(MockOIDCService)
+	mockService.On("StartLogin", "sso").
+		Return(&model.OIDCLoginStart{AuthURL: "https://idp.example.com/authorize?state=abc", State: "sealed"}, nil)
+
+	handler := NewOIDCHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/sso/login", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/api/v1/auth/oidc/:provider/login")
+	c.SetParamNames("provider")
+	c.SetParamValues("sso")
+
+	run(c, handler.Login)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://idp.example.com/authorize?state=abc", rec.Header().Get(echo.HeaderLocation))
+	assert.Contains(t, rec.Header().Get(echo.HeaderSetCookie), oidcStateCookie+"=sealed")
+
+	mockService.AssertExpectations(t)
+}
+
+func TestOIDCHandler_Callback(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		cookie         string
+		mockSetup      func(*MockOIDCService)
+		expectedStatus int
+	}{
+		{
+			name:   "successful login",
+			query:  "?code=abc&state=xyz",
+			cookie: "sealed",
+			mockSetup: func(mockService *MockOIDCService) {
+				mockService.On("CompleteLogin", "sso", mock.AnythingOfType("*model.OIDCCallbackRequest"), "sealed", mock.AnythingOfType("service.ClientInfo")).
+					Return(&model.LoginResponse{
+						TokenResponse: &model.TokenResponse{AccessToken: "token", TokenType: "Bearer"},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "state mismatch",
+			query:  "?code=abc&state=forged",
+			cookie: "sealed",
+			mockSetup: func(mockService *MockOIDCService) {
+				mockService.On("CompleteLogin", "sso", mock.AnythingOfType("*model.OIDCCallbackRequest"), "sealed", mock.AnythingOfType("service.ClientInfo")).
+					Return((*model.LoginResponse)(nil), service.ErrInvalidOIDCState)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "unverified email of existing account",
+			query:  "?code=abc&state=xyz",
+			cookie: "sealed",
+			mockSetup: func(mockService *MockOIDCService) {
+				mockService.On("CompleteLogin", "sso", mock.AnythingOfType("*model.OIDCCallbackRequest"), "sealed", mock.AnythingOfType("service.ClientInfo")).
+					Return((*model.LoginResponse)(nil), service.ErrIdentityEmailConflict)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "missing state",
+			query:          "?code=abc",
+			mockSetup:      func(mockService *MockOIDCService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockOIDCService)
+			tt.mockSetup(mockService)
+
+			handler := NewOIDCHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/sso/callback"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/auth/oidc/:provider/callback")
+			c.SetParamNames("provider")
+			c.SetParamValues("sso")
+
+			run(c, handler.Callback)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"echto/internal/model"
+	"echto/internal/service"
+	"echto/pkg/problem"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PasswordResetHandler struct {
+	passwordResetService service.PasswordResetService
+}
+
+func NewPasswordResetHandler(passwordResetService service.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		passwordResetService: passwordResetService,
+	}
+}
+
+// RequestReset handles POST /api/v1/auth/password-reset
+// @Summary Request password reset
+// @Description Send a single-use password reset link to the given email. The response is the same whether or not an account exists for the email.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.PasswordResetRequest true "Account email"
+// @Success 202 {object} model.SuccessResponse
+// @Failure 400 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /api/v1/auth/password-reset [post]
+func (h *PasswordResetHandler) RequestReset(c echo.Context) error {
+	var req model.PasswordResetRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Request reset
+	if err := h.passwordResetService.RequestReset(c.Request().Context(), &req); err != nil {
+		return serviceError(c, err, "Failed to request password reset")
+	}
+
+	return c.JSON(http.StatusAccepted, model.SuccessResponse{
+		Message: "If an account exists for this email, a password reset link has been sent",
+		Code:    http.StatusAccepted,
+	})
+}
+
+// ConfirmReset handles POST /api/v1/auth/password-reset/confirm
+// @Summary Confirm password reset
+// @Description Set a new password using a password reset token. Every existing session of the user is signed out.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.PasswordResetConfirmRequest true "Reset token and new password"
+// @Success 204 "Password reset successfully"
+// @Failure 400 {object} problem.Details
+// @Failure 409 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /api/v1/auth/password-reset/confirm [post]
+func (h *PasswordResetHandler) ConfirmReset(c echo.Context) error {
+	var req model.PasswordResetConfirmRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Reset password
+	if err := h.passwordResetService.ConfirmReset(c.Request().Context(), &req); err != nil {
+		return serviceError(c, err, "Failed to reset password")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"echto/internal/model"
+	"echto/internal/service"
+	"echto/pkg/validation"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPasswordResetService is a mock implementation of PasswordResetService
+type MockPasswordResetService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetService) RequestReset(ctx context.Context, req *model.PasswordResetRequest) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetService) ConfirmReset(ctx context.Context, req *model.PasswordResetConfirmRequest) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+func TestPasswordResetHandler_RequestReset(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		requestBody    model.PasswordResetRequest
+		mockSetup      func(*MockPasswordResetService)
+		expectedStatus int
+	}{
+		{
+			name:        "known or unknown email",
+			requestBody: model.PasswordResetRequest{Email: "john@example.com"},
+			mockSetup: func(mockService *MockPasswordResetService) {
+				mockService.On("RequestReset", mock.AnythingOfType("*model.PasswordResetRequest")).Return(nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "invalid email",
+			requestBody:    model.PasswordResetRequest{Email: "not-an-email"},
+			mockSetup:      func(mockService *MockPasswordResetService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPasswordResetService)
+			tt.mockSetup(mockService)
+
+			handler := NewPasswordResetHandler(mockService)
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/password-reset", bytes.NewReader(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler.RequestReset)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPasswordResetHandler_ConfirmReset(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		requestBody    model.PasswordResetConfirmRequest
+		mockSetup      func(*MockPasswordResetService)
+		expectedStatus int
+	}{
+		{
+			name:        "successful reset",
+			requestBody: model.PasswordResetConfirmRequest{Token: "valid", NewPassword: "new-password123"},
+			mockSetup: func(mockService *MockPasswordResetService) {
+				mockService.On("ConfirmReset", mock.AnythingOfType("*model.PasswordResetConfirmRequest")).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:        "used or expired token",
+			requestBody: model.PasswordResetConfirmRequest{Token: "used", NewPassword: "new-password123"},
+			mockSetup: func(mockService *MockPasswordResetService) {
+				mockService.On("ConfirmReset", mock.AnythingOfType("*model.PasswordResetConfirmRequest")).
+					Return(service.ErrInvalidResetToken)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockPasswordResetService)
+			tt.mockSetup(mockService)
+
+			handler := NewPasswordResetHandler(mockService)
+
+			reqBody, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/password-reset/confirm", bytes.NewReader(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler.ConfirmReset)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
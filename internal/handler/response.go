@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"echto/internal/service"
+	"echto/pkg/patch"
+	"echto/pkg/problem"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// passwordPolicyError returns a 400 problem listing every password policy rule that was violated
+func passwordPolicyError(policyErr *service.PasswordPolicyError) error {
+	fields := make([]problem.FieldError, len(policyErr.Violations))
+	for i, violation := range policyErr.Violations {
+		fields[i] = problem.FieldError{
+			Field:   policyErr.Field,
+			Rule:    violation.Rule,
+			Param:   violation.Param,
+			Message: violation.Message,
+		}
+	}
+
+	return problem.New(http.StatusBadRequest, "weak_password", "Password does not meet the password policy").WithFields(fields)
+}
+
+// patchError returns the problem for a patch that could not be applied, following the
+// status codes RFC 5789 suggests
+func patchError(err error) error {
+	switch {
+	case errors.Is(err, patch.ErrTestFailed):
+		return problem.New(http.StatusConflict, "patch_test_failed", "A test operation of the patch failed")
+	case errors.Is(err, patch.ErrNotApplicable):
+		return problem.New(http.StatusUnprocessableEntity, "patch_not_applicable", "The patch refers to a location that does not exist")
+	}
+	return problem.New(http.StatusBadRequest, "invalid_patch", "The patch document is malformed")
+}
+
+// checkReadOnlyFields returns a 422 problem listing the members of a patched JSON
+// object that differ from the original, apart from the editable ones
+func checkReadOnlyFields(original, patched []byte, editable ...string) error {
+	var before, after map[string]interface{}
+	if err := json.Unmarshal(original, &before); err != nil {
+		return problem.New(http.StatusInternalServerError, "internal_server_error", "Failed to update user").WithInternal(err)
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return problem.New(http.StatusUnprocessableEntity, "patch_not_applicable", "The patch does not produce a valid user")
+	}
+
+	changed := make(map[string]bool)
+	for _, members := range []map[string]interface{}{before, after} {
+		for name := range members {
+			if !reflect.DeepEqual(before[name], after[name]) {
+				changed[name] = true
+			}
+		}
+	}
+	for _, name := range editable {
+		delete(changed, name)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]problem.FieldError, len(names))
+	for i, name := range names {
+		fields[i] = problem.FieldError{Field: name, Rule: "read_only", Message: "cannot be changed"}
+	}
+	return problem.New(http.StatusUnprocessableEntity, "read_only_field", "The patch changes fields that cannot be edited").WithFields(fields)
+}
+
+// userETag returns the entity tag of a user representation, which changes with every update
+func userETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists etag or is "*".
+// If-None-Match compares weakly, ignoring a W/ prefix; If-Match compares strongly.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// clientInfo describes the device the request comes from
+func clientInfo(c echo.Context) service.ClientInfo {
+	return service.ClientInfo{
+		IPAddress: c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+}
+
+// NewIPExtractor returns how the client IP is determined. X-Forwarded-For is only taken
+// from the comma-separated proxy CIDRs in trustedProxies, so clients cannot pick the
+// address the login throttle counts against; without proxies the connection address is used.
+func NewIPExtractor(trustedProxies string) (echo.IPExtractor, error) {
+	if trustedProxies == "" {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	trustOptions := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(proxy))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		trustOptions = append(trustOptions, echo.TrustIPRange(network))
+	}
+	return echo.ExtractIPFromXFFHeader(trustOptions...), nil
+}
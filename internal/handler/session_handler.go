@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"echto/internal/service"
+	echtoMiddleware "echto/pkg/middleware"
+	"echto/pkg/problem"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// GetSessions handles GET /api/v1/users/me/sessions
+// @Summary List sessions
+// @Description List the devices signed in to the authenticated user's account, most recently seen first. The session the request was made from is marked as current.
+// @Tags Sessions
+// @Produce json
+// @Success 200 {object} model.SessionListResponse
+// @Failure 401 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/me/sessions [get]
+func (h *SessionHandler) GetSessions(c echo.Context) error {
+	claims, ok := echtoMiddleware.ClaimsFromContext(c)
+	if !ok {
+		return problem.New(http.StatusUnauthorized, "unauthorized", "Authentication required")
+	}
+
+	// Get sessions
+	sessions, err := h.sessionService.GetSessions(c.Request().Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		return serviceError(c, err, "Failed to get sessions")
+	}
+
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE /api/v1/users/me/sessions/:sessionId
+// @Summary Revoke session
+// @Description Sign out a single device. Its refresh token stops working and its access tokens are refused immediately.
+// @Tags Sessions
+// @Produce json
+// @Param sessionId path int true "Session ID"
+// @Success 204 "Session revoked successfully"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/me/sessions/{sessionId} [delete]
+func (h *SessionHandler) RevokeSession(c echo.Context) error {
+	userID, ok := echtoMiddleware.UserIDFromContext(c)
+	if !ok {
+		return problem.New(http.StatusUnauthorized, "unauthorized", "Authentication required")
+	}
+
+	// Parse session ID
+	sessionID, err := strconv.ParseUint(c.Param("sessionId"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid session ID")
+	}
+
+	// Revoke session
+	if err := h.sessionService.RevokeSession(c.Request().Context(), userID, uint(sessionID)); err != nil {
+		return serviceError(c, err, "Failed to revoke session")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
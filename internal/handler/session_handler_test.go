@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"echto/internal/model"
+	"echto/internal/service"
+	echtoMiddleware "echto/pkg/middleware"
+	"echto/pkg/token"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSessionService is a mock implementation of SessionService
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) GetSessions(ctx context.Context, userID uint, currentSessionID string) (*model.SessionListResponse, error) {
+	args := m.Called(userID, currentSessionID)
+	return args.Get(0).(*model.SessionListResponse), args.Error(1)
+}
+
+func (m *MockSessionService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	args := m.Called(userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) TouchSession(ctx context.Context, sessionID string, userID uint, ipAddress string) (bool, error) {
+	args := m.Called(sessionID, userID, ipAddress)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestSessionHandler_GetSessions(t *testing.T) {
+	e := echo.New()
+
+	mockService := new(MockSessionService)
+	mockService.On("GetSessions", uint(1), "family-1").Return(&model.SessionListResponse{
+		Sessions: []model.SessionResponse{
+			{ID: 1, Device: "Firefox on Linux", IPAddress: "127.0.0.1", Current: true},
+			{ID: 2, Device: "Safari on iOS", IPAddress: "10.0.0.2"},
+		},
+	}, nil)
+
+	handler := NewSessionHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/sessions", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(echtoMiddleware.ContextKeyClaims, &token.Claims{UserID: 1, SessionID: "family-1"})
+
+	run(c, handler.GetSessions)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"current":true`)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestSessionHandler_RevokeSession(t *testing.T) {
+	e := echo.New()
+
+	tests := []struct {
+		name           string
+		sessionID      string
+		mockSetup      func(*MockSessionService)
+		expectedStatus int
+	}{
+		{
+			name:      "successful revocation",
+			sessionID: "2",
+			mockSetup: func(mockService *MockSessionService) {
+				mockService.On("RevokeSession", uint(1), uint(2)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:      "session of another user",
+			sessionID: "3",
+			mockSetup: func(mockService *MockSessionService) {
+				mockService.On("RevokeSession", uint(1), uint(3)).Return(service.ErrSessionNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid session ID",
+			sessionID:      "abc",
+			mockSetup:      func(mockService *MockSessionService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockSessionService)
+			tt.mockSetup(mockService)
+
+			handler := NewSessionHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me/sessions/"+tt.sessionID, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/users/me/sessions/:sessionId")
+			c.SetParamNames("sessionId")
+			c.SetParamValues(tt.sessionID)
+			c.Set(echtoMiddleware.ContextKeyUserID, uint(1))
+
+			run(c, handler.RevokeSession)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
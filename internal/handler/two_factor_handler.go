@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"echto/internal/model"
+	"echto/internal/service"
+	echtoMiddleware "echto/pkg/middleware"
+	"echto/pkg/problem"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// Enroll handles POST /api/v1/auth/2fa/enroll
+// @Summary Start two-factor enrollment
+// @Description Generate a TOTP secret for the authenticated user. Two-factor authentication is enabled once a code is confirmed.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.TwoFactorEnrollResponse
+// @Failure 401 {object} problem.Details
+// @Failure 409 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/auth/2fa/enroll [post]
+func (h *TwoFactorHandler) Enroll(c echo.Context) error {
+	userID, ok := echtoMiddleware.UserIDFromContext(c)
+	if !ok {
+		return problem.New(http.StatusUnauthorized, "unauthorized", "Authentication required")
+	}
+
+	// Enroll
+	enrollment, err := h.twoFactorService.Enroll(c.Request().Context(), userID)
+	if err != nil {
+		return serviceError(c, err, "Failed to enroll two-factor authentication")
+	}
+
+	return c.JSON(http.StatusOK, enrollment)
+}
+
+// Confirm handles POST /api/v1/auth/2fa/confirm
+// @Summary Confirm two-factor enrollment
+// @Description Enable two-factor authentication with a first TOTP code and receive one-time recovery codes
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorConfirmRequest true "TOTP code"
+// @Success 200 {object} model.RecoveryCodesResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 409 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/auth/2fa/confirm [post]
+func (h *TwoFactorHandler) Confirm(c echo.Context) error {
+	userID, ok := echtoMiddleware.UserIDFromContext(c)
+	if !ok {
+		return problem.New(http.StatusUnauthorized, "unauthorized", "Authentication required")
+	}
+
+	var req model.TwoFactorConfirmRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Confirm enrollment
+	recoveryCodes, err := h.twoFactorService.Confirm(c.Request().Context(), userID, &req)
+	if err != nil {
+		return serviceError(c, err, "Failed to confirm two-factor authentication")
+	}
+
+	return c.JSON(http.StatusOK, recoveryCodes)
+}
@@ -3,101 +3,156 @@ package handler
 import (
 	"echto/internal/model"
 	"echto/internal/service"
-	"echto/pkg/logger"
+	"echto/pkg/patch"
+	"echto/pkg/problem"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 )
 
+// Headers of conditional requests (RFC 7232)
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// maxPatchBytes bounds the patch documents PatchUser reads, which are far smaller in practice
+const maxPatchBytes = 64 << 10
+
 type UserHandler struct {
 	userService service.UserService
-	validator   *validator.Validate
 }
 
 func NewUserHandler(userService service.UserService) *UserHandler {
 	return &UserHandler{
 		userService: userService,
-		validator:   validator.New(),
 	}
 }
 
 // GetUsers handles GET /api/v1/users
 // @Summary Get all users
-// @Description Retrieve a paginated list of users
+// @Description Retrieve a paginated, filtered and sorted list of users, by page number or by cursor. Requires the admin role.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Number of items per page" default(10)
+// @Param pagination query string false "Page by page number or by cursor" Enums(page, cursor) default(page)
+// @Param cursor query string false "next_cursor or prev_cursor of a previous response; implies cursor pagination"
+// @Param include_total query bool false "Count all matching users; defaults to true for page and false for cursor pagination"
+// @Param sort query string false "Comma-separated sort fields (id, name, email, role, created_at, updated_at), each optionally followed by :asc or :desc" example(role,created_at:desc)
+// @Param q query string false "Match users whose name or email contains this text"
+// @Param name query string false "Match users whose name contains this text"
+// @Param email_domain query string false "Match users whose email is at this domain" example(example.com)
+// @Param created_from query string false "Only users created at or after this RFC 3339 time" format(date-time)
+// @Param created_to query string false "Only users created before this RFC 3339 time" format(date-time)
 // @Success 200 {object} model.UserListResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
 // @Router /api/v1/users [get]
 func (h *UserHandler) GetUsers(c echo.Context) error {
-	// Parse query parameters
-	page, _ := strconv.Atoi(c.QueryParam("page"))
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	var query model.UserListQuery
 
-	if page < 1 {
-		page = 1
+	// Bind query parameters
+	if err := c.Bind(&query); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid query parameters")
 	}
-	if limit < 1 {
-		limit = 10
+
+	// Validate query parameters
+	if err := c.Validate(&query); err != nil {
+		return err
 	}
 
 	// Get users from service
-	users, err := h.userService.GetUsers(page, limit)
+	users, err := h.userService.GetUsers(c.Request().Context(), &query)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to get users")
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{
-			Error:   "internal_server_error",
-			Message: "Failed to get users",
-			Code:    http.StatusInternalServerError,
-		})
+		return serviceError(c, err, "Failed to get users")
 	}
 
 	return c.JSON(http.StatusOK, users)
 }
 
+// SearchUsers handles GET /api/v1/users/search
+// @Summary Search users
+// @Description Find users by name or email, tolerating typos where the database supports it. Results are ranked best first, with the matched text highlighted. Requires the admin role.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param q query string true "Search text" minlength(2) maxlength(100)
+// @Param limit query int false "Maximum number of results" default(20) maximum(50)
+// @Success 200 {object} model.UserSearchResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/search [get]
+func (h *UserHandler) SearchUsers(c echo.Context) error {
+	var query model.UserSearchQuery
+
+	// Bind query parameters
+	if err := c.Bind(&query); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid query parameters")
+	}
+
+	// Validate query parameters
+	if err := c.Validate(&query); err != nil {
+		return err
+	}
+
+	// Search users
+	results, err := h.userService.SearchUsers(c.Request().Context(), &query)
+	if err != nil {
+		return serviceError(c, err, "Failed to search users")
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
+
 // GetUser handles GET /api/v1/users/:id
 // @Summary Get user by ID
-// @Description Retrieve a specific user by ID
+// @Description Retrieve a specific user by ID. Users may only read themselves unless they are an admin.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
+// @Param If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} model.UserResponse
-// @Failure 404 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Header 200 {string} ETag "Version of the user"
+// @Success 304 "User has not changed"
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
 // @Router /api/v1/users/{id} [get]
 func (h *UserHandler) GetUser(c echo.Context) error {
 	// Parse user ID
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "invalid_id",
-			Message: "Invalid user ID",
-			Code:    http.StatusBadRequest,
-		})
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
 	}
 
 	// Get user from service
-	user, err := h.userService.GetUser(uint(id))
+	user, err := h.userService.GetUser(c.Request().Context(), uint(id))
 	if err != nil {
-		if err.Error() == "user not found" {
-			return c.JSON(http.StatusNotFound, model.ErrorResponse{
-				Error:   "user_not_found",
-				Message: "User not found",
-				Code:    http.StatusNotFound,
-			})
-		}
-		logger.Log.Error().Err(err).Msg("Failed to get user")
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{
-			Error:   "internal_server_error",
-			Message: "Failed to get user",
-			Code:    http.StatusInternalServerError,
-		})
+		return serviceError(c, err, "Failed to get user")
+	}
+
+	// Let clients revalidate a cached copy
+	etag := userETag(user.Version)
+	c.Response().Header().Set(headerETag, etag)
+	if match := c.Request().Header.Get(headerIfNoneMatch); match != "" && etagMatches(match, etag, true) {
+		return c.NoContent(http.StatusNotModified)
 	}
 
 	return c.JSON(http.StatusOK, user)
@@ -111,47 +166,27 @@ func (h *UserHandler) GetUser(c echo.Context) error {
 // @Produce json
 // @Param user body model.UserCreateRequest true "User data"
 // @Success 201 {object} model.UserResponse
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 409 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Failure 400 {object} problem.Details
+// @Failure 409 {object} problem.Details
+// @Failure 500 {object} problem.Details
 // @Router /api/v1/users [post]
 func (h *UserHandler) CreateUser(c echo.Context) error {
 	var req model.UserCreateRequest
 
 	// Bind request body
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "invalid_request",
-			Message: "Invalid request body",
-			Code:    http.StatusBadRequest,
-		})
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
 	// Validate request
-	if err := h.validator.Struct(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "validation_error",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+	if err := c.Validate(&req); err != nil {
+		return err
 	}
 
 	// Create user
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.CreateUser(c.Request().Context(), &req)
 	if err != nil {
-		if err.Error() == "email already exists" {
-			return c.JSON(http.StatusConflict, model.ErrorResponse{
-				Error:   "email_exists",
-				Message: "Email already exists",
-				Code:    http.StatusConflict,
-			})
-		}
-		logger.Log.Error().Err(err).Msg("Failed to create user")
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{
-			Error:   "internal_server_error",
-			Message: "Failed to create user",
-			Code:    http.StatusInternalServerError,
-		})
+		return serviceError(c, err, "Failed to create user")
 	}
 
 	return c.JSON(http.StatusCreated, user)
@@ -159,115 +194,263 @@ func (h *UserHandler) CreateUser(c echo.Context) error {
 
 // UpdateUser handles PUT /api/v1/users/:id
 // @Summary Update user
-// @Description Update an existing user
+// @Description Update an existing user. Users may only update themselves unless they are an admin.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
+// @Param If-Match header string false "ETag the write is conditional on"
 // @Param user body model.UserUpdateRequest true "User data"
 // @Success 200 {object} model.UserResponse
-// @Failure 400 {object} model.ErrorResponse
-// @Failure 404 {object} model.ErrorResponse
-// @Failure 409 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Header 200 {string} ETag "New version of the user"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 409 {object} problem.Details
+// @Failure 412 {object} problem.Details
+// @Failure 428 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
 // @Router /api/v1/users/{id} [put]
 func (h *UserHandler) UpdateUser(c echo.Context) error {
 	// Parse user ID
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "invalid_id",
-			Message: "Invalid user ID",
-			Code:    http.StatusBadRequest,
-		})
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
 	}
 
 	var req model.UserUpdateRequest
 
 	// Bind request body
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "invalid_request",
-			Message: "Invalid request body",
-			Code:    http.StatusBadRequest,
-		})
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
 	}
 
 	// Validate request
-	if err := h.validator.Struct(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "validation_error",
-			Message: err.Error(),
-			Code:    http.StatusBadRequest,
-		})
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Evaluate If-Match
+	version, err := h.ifMatch(c, uint(id))
+	if err != nil {
+		return err
 	}
 
 	// Update user
-	user, err := h.userService.UpdateUser(uint(id), &req)
+	user, err := h.userService.UpdateUser(c.Request().Context(), uint(id), version, &req)
 	if err != nil {
-		if err.Error() == "user not found" {
-			return c.JSON(http.StatusNotFound, model.ErrorResponse{
-				Error:   "user_not_found",
-				Message: "User not found",
-				Code:    http.StatusNotFound,
-			})
+		return serviceError(c, err, "Failed to update user")
+	}
+
+	c.Response().Header().Set(headerETag, userETag(user.Version))
+	return c.JSON(http.StatusOK, user)
+}
+
+// PatchUser handles PATCH /api/v1/users/:id
+// @Summary Patch user
+// @Description Apply a JSON merge patch (RFC 7396) or JSON patch (RFC 6902) to the user representation returned by GET. Only name and email can be changed, and the patched user is validated like a new one. Users may only patch themselves unless they are an admin.
+// @Tags Users
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param If-Match header string false "ETag the write is conditional on"
+// @Param patch body object true "Merge patch or JSON patch document"
+// @Success 200 {object} model.UserResponse
+// @Header 200 {string} ETag "New version of the user"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 409 {object} problem.Details
+// @Failure 413 {object} problem.Details
+// @Failure 415 {object} problem.Details
+// @Failure 422 {object} problem.Details
+// @Failure 412 {object} problem.Details
+// @Failure 428 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id} [patch]
+func (h *UserHandler) PatchUser(c echo.Context) error {
+	// Parse user ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	// Only the patch formats are accepted, a plain JSON body would be ambiguous
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != patch.MIMEMergePatch && mediaType != patch.MIMEJSONPatch {
+		c.Response().Header().Set("Accept-Patch", patch.MIMEMergePatch+", "+patch.MIMEJSONPatch)
+		return problem.New(http.StatusUnsupportedMediaType, "unsupported_media_type", "Send a patch as application/merge-patch+json or application/json-patch+json")
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, maxPatchBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return problem.New(http.StatusRequestEntityTooLarge, "request_entity_too_large", "The patch document is too large")
 		}
-		if err.Error() == "email already exists" {
-			return c.JSON(http.StatusConflict, model.ErrorResponse{
-				Error:   "email_exists",
-				Message: "Email already exists",
-				Code:    http.StatusConflict,
-			})
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Apply the patch to the current representation. The write is always based on the
+	// version that was patched, If-Match only decides whether a conflict is a failed
+	// precondition or a concurrent update.
+	user, err := h.userService.GetUser(c.Request().Context(), uint(id))
+	if err != nil {
+		return serviceError(c, err, "Failed to get user")
+	}
+	var version uint
+	if match := c.Request().Header.Get(headerIfMatch); match != "" {
+		if !etagMatches(match, userETag(user.Version), false) {
+			return serviceError(c, service.ErrVersionMismatch, "Failed to update user")
 		}
-		logger.Log.Error().Err(err).Msg("Failed to update user")
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{
-			Error:   "internal_server_error",
-			Message: "Failed to update user",
-			Code:    http.StatusInternalServerError,
-		})
+		version = user.Version
+	}
+	current, err := json.Marshal(user)
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "internal_server_error", "Failed to update user").WithInternal(err)
 	}
+	patched, err := patch.Apply(mediaType, current, body)
+	if err != nil {
+		return patchError(err)
+	}
+	if err := checkReadOnlyFields(current, patched, "name", "email"); err != nil {
+		return err
+	}
+
+	var req model.UserReplaceRequest
+	if err := json.Unmarshal(patched, &req); err != nil {
+		return problem.New(http.StatusUnprocessableEntity, "patch_not_applicable", "The patch does not produce a valid user")
+	}
+
+	// Validate the patched user
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Replace user
+	updated, err := h.userService.ReplaceUser(c.Request().Context(), uint(id), version, user.Version, &req)
+	if err != nil {
+		return serviceError(c, err, "Failed to update user")
+	}
+
+	c.Response().Header().Set(headerETag, userETag(updated.Version))
+	return c.JSON(http.StatusOK, updated)
+}
 
+// UpdateUserRole handles PUT /api/v1/users/:id/role
+// @Summary Update user role
+// @Description Change the role of a user. Requires the admin role.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param If-Match header string false "ETag the write is conditional on"
+// @Param role body model.UserRoleUpdateRequest true "Role data"
+// @Success 200 {object} model.UserResponse
+// @Header 200 {string} ETag "New version of the user"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 409 {object} problem.Details
+// @Failure 412 {object} problem.Details
+// @Failure 428 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
+// @Router /api/v1/users/{id}/role [put]
+func (h *UserHandler) UpdateUserRole(c echo.Context) error {
+	// Parse user ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	var req model.UserRoleUpdateRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return problem.New(http.StatusBadRequest, "invalid_request", "Invalid request body")
+	}
+
+	// Validate request
+	if err := c.Validate(&req); err != nil {
+		return err
+	}
+
+	// Evaluate If-Match
+	version, err := h.ifMatch(c, uint(id))
+	if err != nil {
+		return err
+	}
+
+	// Update role
+	user, err := h.userService.UpdateUserRole(c.Request().Context(), uint(id), version, &req)
+	if err != nil {
+		return serviceError(c, err, "Failed to update user role")
+	}
+
+	c.Response().Header().Set(headerETag, userETag(user.Version))
 	return c.JSON(http.StatusOK, user)
 }
 
 // DeleteUser handles DELETE /api/v1/users/:id
 // @Summary Delete user
-// @Description Delete a user by ID
+// @Description Delete a user by ID. Requires the admin role.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
+// @Param If-Match header string false "ETag the write is conditional on"
 // @Success 204 "User deleted successfully"
-// @Failure 404 {object} model.ErrorResponse
-// @Failure 500 {object} model.ErrorResponse
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details
+// @Failure 409 {object} problem.Details
+// @Failure 412 {object} problem.Details
+// @Failure 428 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Security ApiKeyAuth
 // @Router /api/v1/users/{id} [delete]
 func (h *UserHandler) DeleteUser(c echo.Context) error {
 	// Parse user ID
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "invalid_id",
-			Message: "Invalid user ID",
-			Code:    http.StatusBadRequest,
-		})
+		return problem.New(http.StatusBadRequest, "invalid_id", "Invalid user ID")
+	}
+
+	// Evaluate If-Match
+	version, err := h.ifMatch(c, uint(id))
+	if err != nil {
+		return err
 	}
 
 	// Delete user
-	if err := h.userService.DeleteUser(uint(id)); err != nil {
-		if err.Error() == "user not found" {
-			return c.JSON(http.StatusNotFound, model.ErrorResponse{
-				Error:   "user_not_found",
-				Message: "User not found",
-				Code:    http.StatusNotFound,
-			})
-		}
-		logger.Log.Error().Err(err).Msg("Failed to delete user")
-		return c.JSON(http.StatusInternalServerError, model.ErrorResponse{
-			Error:   "internal_server_error",
-			Message: "Failed to delete user",
-			Code:    http.StatusInternalServerError,
-		})
+	if err := h.userService.DeleteUser(c.Request().Context(), uint(id), version); err != nil {
+		return serviceError(c, err, "Failed to delete user")
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// ifMatch evaluates the If-Match header of a write to a user and returns the version of
+// the user the write is conditional on, or 0 when the header is absent
+func (h *UserHandler) ifMatch(c echo.Context, id uint) (uint, error) {
+	match := c.Request().Header.Get(headerIfMatch)
+	if match == "" {
+		return 0, nil
+	}
+
+	user, err := h.userService.GetUser(c.Request().Context(), id)
+	if err != nil {
+		return 0, serviceError(c, err, "Failed to get user")
+	}
+	if !etagMatches(match, userETag(user.Version), false) {
+		return 0, serviceError(c, service.ErrVersionMismatch, "Failed to get user")
+	}
+	return user.Version, nil
+}
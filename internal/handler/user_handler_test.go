@@ -2,12 +2,19 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"echto/internal/model"
+	"echto/internal/service"
+	"echto/pkg/password"
+	"echto/pkg/problem"
+	"echto/pkg/validation"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -19,39 +26,56 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) CreateUser(req *model.UserCreateRequest) (*model.UserResponse, error) {
+func (m *MockUserService) CreateUser(ctx context.Context, req *model.UserCreateRequest) (*model.UserResponse, error) {
 	args := m.Called(req)
 	return args.Get(0).(*model.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) GetUser(id uint) (*model.UserResponse, error) {
+func (m *MockUserService) GetUser(ctx context.Context, id uint) (*model.UserResponse, error) {
 	args := m.Called(id)
 	return args.Get(0).(*model.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) GetUsers(page, limit int) (*model.UserListResponse, error) {
-	args := m.Called(page, limit)
+func (m *MockUserService) GetUsers(ctx context.Context, query *model.UserListQuery) (*model.UserListResponse, error) {
+	args := m.Called(query)
 	return args.Get(0).(*model.UserListResponse), args.Error(1)
 }
 
-func (m *MockUserService) UpdateUser(id uint, req *model.UserUpdateRequest) (*model.UserResponse, error) {
-	args := m.Called(id, req)
+func (m *MockUserService) SearchUsers(ctx context.Context, query *model.UserSearchQuery) (*model.UserSearchResponse, error) {
+	args := m.Called(query)
+	return args.Get(0).(*model.UserSearchResponse), args.Error(1)
+}
+
+func (m *MockUserService) UpdateUser(ctx context.Context, id, version uint, req *model.UserUpdateRequest) (*model.UserResponse, error) {
+	args := m.Called(id, version, req)
 	return args.Get(0).(*model.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) DeleteUser(id uint) error {
-	args := m.Called(id)
+func (m *MockUserService) ReplaceUser(ctx context.Context, id, version, basedOn uint, req *model.UserReplaceRequest) (*model.UserResponse, error) {
+	args := m.Called(id, version, basedOn, req)
+	return args.Get(0).(*model.UserResponse), args.Error(1)
+}
+
+func (m *MockUserService) UpdateUserRole(ctx context.Context, id, version uint, req *model.UserRoleUpdateRequest) (*model.UserResponse, error) {
+	args := m.Called(id, version, req)
+	return args.Get(0).(*model.UserResponse), args.Error(1)
+}
+
+func (m *MockUserService) DeleteUser(ctx context.Context, id, version uint) error {
+	args := m.Called(id, version)
 	return args.Error(0)
 }
 
 func TestUserHandler_CreateUser(t *testing.T) {
 	e := echo.New()
+	e.Validator = validation.New()
 
 	tests := []struct {
 		name           string
 		requestBody    model.UserCreateRequest
 		mockSetup      func(*MockUserService)
 		expectedStatus int
+		expectedFields []problem.FieldError
 	}{
 		{
 			name: "successful user creation",
@@ -79,10 +103,40 @@ func TestUserHandler_CreateUser(t *testing.T) {
 			},
 			mockSetup: func(mockService *MockUserService) {
 				mockService.On("CreateUser", mock.AnythingOfType("*model.UserCreateRequest")).
-					Return((*model.UserResponse)(nil), errors.New("email already exists"))
+					Return((*model.UserResponse)(nil), service.ErrEmailExists)
 			},
 			expectedStatus: http.StatusConflict,
 		},
+		{
+			name: "weak password",
+			requestBody: model.UserCreateRequest{
+				Name:     "John Doe",
+				Email:    "john@example.com",
+				Password: "john1234",
+			},
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("CreateUser", mock.AnythingOfType("*model.UserCreateRequest")).
+					Return((*model.UserResponse)(nil), &service.PasswordPolicyError{
+						Field:      "password",
+						Violations: []password.Violation{{Rule: "user_info", Message: "must not contain your name or email"}},
+					})
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid fields",
+			requestBody: model.UserCreateRequest{
+				Name:     "J",
+				Email:    "not-an-email",
+				Password: "password123",
+			},
+			mockSetup:      func(mockService *MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []problem.FieldError{
+				{Field: "name", Rule: "min", Param: "2", Message: "must be at least 2 characters long"},
+				{Field: "email", Rule: "email", Message: "must be a valid email address"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -98,10 +152,194 @@ func TestUserHandler_CreateUser(t *testing.T) {
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 
-			err := handler.CreateUser(c)
-			assert.NoError(t, err)
+			run(c, handler.CreateUser)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			if tt.expectedFields != nil {
+				var details problem.Details
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+				assert.Equal(t, "validation_error", details.Code)
+				assert.Equal(t, tt.expectedFields, details.Fields)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_GetUsers(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	createdFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	includeTotal := false
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*MockUserService)
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:  "filtered and sorted list",
+			query: "page=2&limit=5&sort=role,created_at:desc&q=john&email_domain=example.com&created_from=2024-01-01T00:00:00Z",
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUsers", &model.UserListQuery{
+					Page:        2,
+					Limit:       5,
+					Sort:        "role,created_at:desc",
+					Query:       "john",
+					EmailDomain: "example.com",
+					CreatedFrom: &createdFrom,
+				}).Return(&model.UserListResponse{Users: []model.UserResponse{}, Page: 2, Limit: 5}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "next cursor page without total",
+			query: "cursor=eyJpZCI6NDJ9&include_total=false&limit=20",
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUsers", &model.UserListQuery{
+					Limit:        20,
+					Cursor:       "eyJpZCI6NDJ9",
+					IncludeTotal: &includeTotal,
+				}).Return(&model.UserListResponse{Users: []model.UserResponse{}, Limit: 20}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "stale cursor",
+			query: "cursor=eyJpZCI6NDJ9&sort=name",
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUsers", mock.AnythingOfType("*model.UserListQuery")).
+					Return((*model.UserListResponse)(nil), service.ErrInvalidCursor)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_cursor",
+		},
+		{
+			name:           "unknown pagination mode",
+			query:          "pagination=offset",
+			mockSetup:      func(mockService *MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "validation_error",
+		},
+		{
+			name:  "unsupported sort field",
+			query: "sort=password",
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUsers", mock.AnythingOfType("*model.UserListQuery")).
+					Return((*model.UserListResponse)(nil), service.ErrInvalidSort)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_sort",
+		},
+		{
+			name:           "malformed date",
+			query:          "created_to=yesterday",
+			mockSetup:      func(mockService *MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_request",
+		},
+		{
+			name:           "invalid email domain",
+			query:          "email_domain=not%20a%20domain",
+			mockSetup:      func(mockService *MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "validation_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			tt.mockSetup(mockService)
+
+			handler := NewUserHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/users?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler.GetUsers)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			if tt.expectedCode != "" {
+				var details problem.Details
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+				assert.Equal(t, tt.expectedCode, details.Code)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_SearchUsers(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*MockUserService)
+		expectedStatus int
+		expectedFields []problem.FieldError
+	}{
+		{
+			name:  "ranked results",
+			query: "q=jhon&limit=5",
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("SearchUsers", &model.UserSearchQuery{Query: "jhon", Limit: 5}).
+					Return(&model.UserSearchResponse{Results: []model.UserSearchResult{{
+						User:       model.UserResponse{ID: 1, Name: "John Doe", Email: "john@example.com"},
+						Score:      0.6,
+						Highlights: model.UserSearchHighlights{Name: "John Doe", Email: "john@example.com"},
+					}}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing query",
+			query:          "limit=5",
+			mockSetup:      func(mockService *MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []problem.FieldError{
+				{Field: "q", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name:           "query too short",
+			query:          "q=j",
+			mockSetup:      func(mockService *MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedFields: []problem.FieldError{
+				{Field: "q", Rule: "min", Param: "2", Message: "must be at least 2 characters long"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			tt.mockSetup(mockService)
+
+			handler := NewUserHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/users/search?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler.SearchUsers)
 			assert.Equal(t, tt.expectedStatus, rec.Code)
 
+			if tt.expectedFields != nil {
+				var details problem.Details
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+				assert.Equal(t, tt.expectedFields, details.Fields)
+			}
+
 			mockService.AssertExpectations(t)
 		})
 	}
@@ -109,32 +347,58 @@ func TestUserHandler_CreateUser(t *testing.T) {
 
 func TestUserHandler_GetUser(t *testing.T) {
 	e := echo.New()
+	e.Validator = validation.New()
+
+	user := &model.UserResponse{
+		ID:      1,
+		Name:    "John Doe",
+		Email:   "john@example.com",
+		Version: 3,
+	}
 
 	tests := []struct {
 		name           string
 		userID         string
+		ifNoneMatch    string
 		mockSetup      func(*MockUserService)
 		expectedStatus int
+		expectedETag   string
 	}{
 		{
 			name:   "successful user retrieval",
 			userID: "1",
 			mockSetup: func(mockService *MockUserService) {
-				mockService.On("GetUser", uint(1)).
-					Return(&model.UserResponse{
-						ID:    1,
-						Name:  "John Doe",
-						Email: "john@example.com",
-					}, nil)
+				mockService.On("GetUser", uint(1)).Return(user, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"3"`,
+		},
+		{
+			name:        "cached copy is current",
+			userID:      "1",
+			ifNoneMatch: `"2", W/"3"`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(user, nil)
+			},
+			expectedStatus: http.StatusNotModified,
+			expectedETag:   `"3"`,
+		},
+		{
+			name:        "cached copy is outdated",
+			userID:      "1",
+			ifNoneMatch: `"2"`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(user, nil)
 			},
 			expectedStatus: http.StatusOK,
+			expectedETag:   `"3"`,
 		},
 		{
 			name:   "user not found",
 			userID: "999",
 			mockSetup: func(mockService *MockUserService) {
 				mockService.On("GetUser", uint(999)).
-					Return((*model.UserResponse)(nil), errors.New("user not found"))
+					Return((*model.UserResponse)(nil), service.ErrUserNotFound)
 			},
 			expectedStatus: http.StatusNotFound,
 		},
@@ -148,15 +412,388 @@ func TestUserHandler_GetUser(t *testing.T) {
 			handler := NewUserHandler(mockService)
 
 			req := httptest.NewRequest(http.MethodGet, "/users/"+tt.userID, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 			c.SetPath("/users/:id")
 			c.SetParamNames("id")
 			c.SetParamValues(tt.userID)
 
-			err := handler.GetUser(c)
-			assert.NoError(t, err)
+			run(c, handler.GetUser)
 			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedETag, rec.Header().Get("ETag"))
+			if tt.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, rec.Body.String())
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_UpdateUser(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	req := &model.UserUpdateRequest{Name: "Jane Doe"}
+	current := &model.UserResponse{ID: 1, Name: "John Doe", Email: "john@example.com", Version: 3}
+	updated := &model.UserResponse{ID: 1, Name: "Jane Doe", Email: "john@example.com", Version: 4}
+
+	tests := []struct {
+		name           string
+		ifMatch        string
+		mockSetup      func(*MockUserService)
+		expectedStatus int
+		expectedCode   string
+		expectedETag   string
+	}{
+		{
+			name: "unconditional update",
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("UpdateUser", uint(1), uint(0), req).Return(updated, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"4"`,
+		},
+		{
+			name:    "matching If-Match",
+			ifMatch: `"3"`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+				mockService.On("UpdateUser", uint(1), uint(3), req).Return(updated, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedETag:   `"4"`,
+		},
+		{
+			name:    "stale If-Match",
+			ifMatch: `"2"`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "precondition_failed",
+		},
+		{
+			name:    "weak If-Match never matches",
+			ifMatch: `W/"3"`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "precondition_failed",
+		},
+		{
+			name: "If-Match required",
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("UpdateUser", uint(1), uint(0), req).
+					Return((*model.UserResponse)(nil), service.ErrVersionRequired)
+			},
+			expectedStatus: http.StatusPreconditionRequired,
+			expectedCode:   "precondition_required",
+		},
+		{
+			name: "concurrent update",
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("UpdateUser", uint(1), uint(0), req).
+					Return((*model.UserResponse)(nil), fmt.Errorf("failed to update user: %w", service.ErrConcurrentUpdate))
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "concurrent_update",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			tt.mockSetup(mockService)
+
+			handler := NewUserHandler(mockService)
+
+			reqBody, _ := json.Marshal(req)
+			httpReq := httptest.NewRequest(http.MethodPut, "/users/1", bytes.NewReader(reqBody))
+			httpReq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.ifMatch != "" {
+				httpReq.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httpReq, rec)
+			c.SetPath("/users/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			run(c, handler.UpdateUser)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedETag, rec.Header().Get("ETag"))
+
+			if tt.expectedCode != "" {
+				var details problem.Details
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+				assert.Equal(t, tt.expectedCode, details.Code)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_DeleteUser(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	current := &model.UserResponse{ID: 1, Name: "John Doe", Email: "john@example.com", Version: 3}
+
+	tests := []struct {
+		name           string
+		ifMatch        string
+		mockSetup      func(*MockUserService)
+		expectedStatus int
+	}{
+		{
+			name:    "matching If-Match",
+			ifMatch: `"3"`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+				mockService.On("DeleteUser", uint(1), uint(3)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:    "any version",
+			ifMatch: "*",
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+				mockService.On("DeleteUser", uint(1), uint(3)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:    "stale If-Match",
+			ifMatch: `"2"`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:    "modified after If-Match was checked",
+			ifMatch: `"3"`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+				mockService.On("DeleteUser", uint(1), uint(3)).Return(service.ErrVersionMismatch)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			tt.mockSetup(mockService)
+
+			handler := NewUserHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+			req.Header.Set("If-Match", tt.ifMatch)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/users/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			run(c, handler.DeleteUser)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_PatchUser(t *testing.T) {
+	e := echo.New()
+	e.Validator = validation.New()
+
+	current := &model.UserResponse{
+		ID:      1,
+		Name:    "John Doe",
+		Email:   "john@example.com",
+		Role:    "user",
+		Version: 3,
+	}
+
+	tests := []struct {
+		name           string
+		contentType    string
+		ifMatch        string
+		body           string
+		mockSetup      func(*MockUserService)
+		expectedStatus int
+		expectedCode   string
+		expectedFields []problem.FieldError
+	}{
+		{
+			name:        "merge patch",
+			contentType: "application/merge-patch+json",
+			body:        `{"name": "Jane Doe"}`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+				mockService.On("ReplaceUser", uint(1), uint(0), uint(3), &model.UserReplaceRequest{Name: "Jane Doe", Email: "john@example.com"}).
+					Return(&model.UserResponse{ID: 1, Name: "Jane Doe", Email: "john@example.com"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "json patch",
+			contentType: "application/json-patch+json; charset=utf-8",
+			body:        `[{"op": "test", "path": "/email", "value": "john@example.com"}, {"op": "replace", "path": "/email", "value": "jane@example.com"}]`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+				mockService.On("ReplaceUser", uint(1), uint(0), uint(3), &model.UserReplaceRequest{Name: "John Doe", Email: "jane@example.com"}).
+					Return(&model.UserResponse{ID: 1, Name: "John Doe", Email: "jane@example.com"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "cleared field is validated",
+			contentType: "application/merge-patch+json",
+			body:        `{"name": null}`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "validation_error",
+			expectedFields: []problem.FieldError{
+				{Field: "name", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name:        "read-only field",
+			contentType: "application/merge-patch+json",
+			body:        `{"role": "admin", "name": "Jane Doe"}`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   "read_only_field",
+			expectedFields: []problem.FieldError{
+				{Field: "role", Rule: "read_only", Message: "cannot be changed"},
+			},
+		},
+		{
+			name:        "failed test operation",
+			contentType: "application/json-patch+json",
+			body:        `[{"op": "test", "path": "/name", "value": "Someone Else"}, {"op": "replace", "path": "/name", "value": "Jane Doe"}]`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "patch_test_failed",
+		},
+		{
+			name:        "malformed patch",
+			contentType: "application/json-patch+json",
+			body:        `{"op": "replace"}`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "invalid_patch",
+		},
+		{
+			name:        "matching If-Match",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"3"`,
+			body:        `{"name": "Jane Doe"}`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+				mockService.On("ReplaceUser", uint(1), uint(3), uint(3), &model.UserReplaceRequest{Name: "Jane Doe", Email: "john@example.com"}).
+					Return(&model.UserResponse{ID: 1, Name: "Jane Doe", Email: "john@example.com", Version: 4}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "stale If-Match",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"2"`,
+			body:        `{"name": "Jane Doe"}`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   "precondition_failed",
+		},
+		{
+			name:        "concurrent update",
+			contentType: "application/merge-patch+json",
+			body:        `{"name": "Jane Doe"}`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return(current, nil)
+				mockService.On("ReplaceUser", uint(1), uint(0), uint(3), &model.UserReplaceRequest{Name: "Jane Doe", Email: "john@example.com"}).
+					Return((*model.UserResponse)(nil), service.ErrConcurrentUpdate)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   "concurrent_update",
+		},
+		{
+			name:           "plain json",
+			contentType:    echo.MIMEApplicationJSON,
+			body:           `{"name": "Jane Doe"}`,
+			mockSetup:      func(mockService *MockUserService) {},
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "unsupported_media_type",
+		},
+		{
+			name:           "patch too large",
+			contentType:    "application/merge-patch+json",
+			body:           `{"name": "` + strings.Repeat("a", maxPatchBytes) + `"}`,
+			mockSetup:      func(mockService *MockUserService) {},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   "request_entity_too_large",
+		},
+		{
+			name:        "user not found",
+			contentType: "application/merge-patch+json",
+			body:        `{"name": "Jane Doe"}`,
+			mockSetup: func(mockService *MockUserService) {
+				mockService.On("GetUser", uint(1)).Return((*model.UserResponse)(nil), service.ErrUserNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "user_not_found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			tt.mockSetup(mockService)
+
+			handler := NewUserHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, tt.contentType)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/users/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			run(c, handler.PatchUser)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+
+			if tt.expectedCode != "" {
+				var details problem.Details
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+				assert.Equal(t, tt.expectedCode, details.Code)
+				assert.Equal(t, tt.expectedFields, details.Fields)
+			}
+			if tt.expectedStatus == http.StatusUnsupportedMediaType {
+				assert.Contains(t, rec.Header().Get("Accept-Patch"), "application/merge-patch+json")
+			}
 
 			mockService.AssertExpectations(t)
 		})
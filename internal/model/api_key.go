@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// APIKeyCreateRequest represents the request payload for creating an API key
+type APIKeyCreateRequest struct {
+	Name          string   `json:"name" validate:"required,min=2,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=users:read users:write"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=3650"`
+}
+
+// APIKeyResponse represents an API key without its secret
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyCreateResponse represents a newly created API key. Key is shown only once.
+type APIKeyCreateResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// APIKeyListResponse represents the response payload for the API keys of a user
+type APIKeyListResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
@@ -0,0 +1,94 @@
+package model
+
+// LoginRequest represents the request payload for logging in
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// RefreshRequest represents the request payload for rotating a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutRequest represents the request payload for logging out
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// PasswordChangeRequest represents the request payload for changing a password
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,nefield=CurrentPassword"`
+}
+
+// PasswordResetRequest represents the request payload for starting a password reset
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// PasswordResetConfirmRequest represents the request payload for completing a password reset
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// EmailVerificationConfirmRequest represents the request payload for confirming an email address
+type EmailVerificationConfirmRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// EmailVerificationResendRequest represents the request payload for resending a verification link
+type EmailVerificationResendRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// TwoFactorConfirmRequest represents the request payload for confirming 2FA enrollment
+type TwoFactorConfirmRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// TwoFactorVerifyRequest represents the request payload for the second login step.
+// Code is either a TOTP code or a recovery code.
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+// TwoFactorEnrollResponse represents a pending TOTP enrollment
+type TwoFactorEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse represents freshly generated recovery codes, shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginResponse represents the result of a login. When the account has two-factor
+// authentication enabled, no tokens are issued and ChallengeToken must be sent to
+// the verify endpoint together with a code.
+type LoginResponse struct {
+	*TokenResponse
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+// TokenResponse represents an issued access and refresh token pair
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// ImpersonationResponse represents an access token issued to an admin acting as another
+// user. It comes without a refresh token and expires after a short time.
+type ImpersonationResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	ActorID     uint   `json:"actor_id"`
+	SubjectID   uint   `json:"subject_id"`
+}
@@ -0,0 +1,16 @@
+package model
+
+// OIDCCallbackRequest represents the query parameters a provider redirects back with
+type OIDCCallbackRequest struct {
+	Code             string `query:"code"`
+	State            string `query:"state" validate:"required"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
+}
+
+// OIDCLoginStart carries what the handler needs to send the user agent to a provider.
+// State is the sealed login state to keep in a cookie until the callback.
+type OIDCLoginStart struct {
+	AuthURL string
+	State   string
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// SessionResponse represents a device signed in to the account
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Current marks the session the request was made from
+	Current bool `json:"current"`
+}
+
+// SessionListResponse represents the response payload for the sessions of a user
+type SessionListResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
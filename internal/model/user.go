@@ -6,7 +6,7 @@ import "time"
 type UserCreateRequest struct {
 	Name     string `json:"name" validate:"required,min=2,max=100"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
 }
 
 // UserUpdateRequest represents the request payload for updating a user
@@ -15,21 +15,85 @@ type UserUpdateRequest struct {
 	Email string `json:"email" validate:"omitempty,email"`
 }
 
+// UserReplaceRequest represents the editable fields of a user as a whole, as they are
+// after a PATCH has been applied to the user
+type UserReplaceRequest struct {
+	Name  string `json:"name" validate:"required,min=2,max=100"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+// UserRoleUpdateRequest represents the request payload for changing a user's role
+type UserRoleUpdateRequest struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
+
+// UserListQuery represents the query parameters for listing users. Sort is a
+// comma-separated list of fields, each optionally followed by :asc or :desc, and
+// CreatedFrom and CreatedTo bound the creation time as [from, to). Lists are paged
+// by page number unless Pagination is "cursor" or a Cursor is given.
+type UserListQuery struct {
+	Page         int        `query:"page"`
+	Limit        int        `query:"limit"`
+	Pagination   string     `query:"pagination" validate:"omitempty,oneof=page cursor"`
+	Cursor       string     `query:"cursor" validate:"max=1024"`
+	IncludeTotal *bool      `query:"include_total"`
+	Sort         string     `query:"sort" validate:"max=200"`
+	Query        string     `query:"q" validate:"max=100"`
+	Name         string     `query:"name" validate:"max=100"`
+	EmailDomain  string     `query:"email_domain" validate:"omitempty,fqdn"`
+	CreatedFrom  *time.Time `query:"created_from"`
+	CreatedTo    *time.Time `query:"created_to"`
+}
+
+// UserSearchQuery represents the query parameters for searching users
+type UserSearchQuery struct {
+	Query string `query:"q" validate:"required,min=2,max=100"`
+	Limit int    `query:"limit"`
+}
+
 // UserResponse represents the response payload for user data
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               uint       `json:"id"`
+	Name             string     `json:"name"`
+	Email            string     `json:"email"`
+	Role             string     `json:"role"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	// Version is sent as the ETag header rather than in the body
+	Version uint `json:"-"`
 }
 
-// UserListResponse represents the response payload for user list
+// UserListResponse represents the response payload for user list. Page is set
+// when paging by page number and the cursors when paging by cursor; Total is left
+// out when it was not requested.
 type UserListResponse struct {
-	Users []UserResponse `json:"users"`
-	Total int64          `json:"total"`
-	Page  int            `json:"page"`
-	Limit int            `json:"limit"`
+	Users      []UserResponse `json:"users"`
+	Total      *int64         `json:"total,omitempty"`
+	Page       int            `json:"page,omitempty"`
+	Limit      int            `json:"limit"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+}
+
+// UserSearchHighlights holds the user fields with the matched text wrapped in
+// <mark> tags. The fields are HTML-escaped.
+type UserSearchHighlights struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// UserSearchResult represents a user found by a search
+type UserSearchResult struct {
+	User       UserResponse         `json:"user"`
+	Score      float64              `json:"score"`
+	Highlights UserSearchHighlights `json:"highlights"`
+}
+
+// UserSearchResponse represents the response payload for a user search, best match first
+type UserSearchResponse struct {
+	Results []UserSearchResult `json:"results"`
 }
 
 type SuccessResponse struct {
@@ -37,10 +101,3 @@ type SuccessResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 	Code    int         `json:"code"`
 }
-
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
-}
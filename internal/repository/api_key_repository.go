@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"echto/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, apiKey *entity.APIKey) error
+	GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+	GetActiveByUser(ctx context.Context, userID uint) ([]entity.APIKey, error)
+	Revoke(ctx context.Context, userID, id uint) (bool, error)
+	TouchLastUsed(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, apiKey *entity.APIKey) error {
+	return conn(ctx, r.db).Create(apiKey).Error
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	var apiKey entity.APIKey
+	err := conn(ctx, r.db).Where("key_hash = ?", keyHash).First(&apiKey).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// GetActiveByUser returns the keys of a user that have not been revoked, newest first
+func (r *apiKeyRepository) GetActiveByUser(ctx context.Context, userID uint) ([]entity.APIKey, error) {
+	var apiKeys []entity.APIKey
+	err := conn(ctx, r.db).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&apiKeys).Error
+	if err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+// Revoke revokes an active key of the user. It reports false when no such key exists.
+func (r *apiKeyRepository) Revoke(ctx context.Context, userID, id uint) (bool, error) {
+	result := conn(ctx, r.db).Model(&entity.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	return conn(ctx, r.db).Model(&entity.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).Error
+}
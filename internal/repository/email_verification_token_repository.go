@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"echto/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EmailVerificationTokenRepository interface {
+	Create(ctx context.Context, verificationToken *entity.EmailVerificationToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*entity.EmailVerificationToken, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	InvalidateByUser(ctx context.Context, userID uint) error
+}
+
+type emailVerificationTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationTokenRepository(db *gorm.DB) EmailVerificationTokenRepository {
+	return &emailVerificationTokenRepository{db: db}
+}
+
+func (r *emailVerificationTokenRepository) Create(ctx context.Context, verificationToken *entity.EmailVerificationToken) error {
+	return conn(ctx, r.db).Create(verificationToken).Error
+}
+
+func (r *emailVerificationTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.EmailVerificationToken, error) {
+	var verificationToken entity.EmailVerificationToken
+	err := conn(ctx, r.db).Where("token_hash = ?", tokenHash).First(&verificationToken).Error
+	if err != nil {
+		return nil, err
+	}
+	return &verificationToken, nil
+}
+
+// MarkUsed consumes the token. It reports false when the token had already been used.
+func (r *emailVerificationTokenRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := conn(ctx, r.db).Model(&entity.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateByUser consumes every outstanding token of the user
+func (r *emailVerificationTokenRepository) InvalidateByUser(ctx context.Context, userID uint) error {
+	return conn(ctx, r.db).Model(&entity.EmailVerificationToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"echto/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LinkedIdentityRepository interface {
+	Create(ctx context.Context, identity *entity.LinkedIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*entity.LinkedIdentity, error)
+	TouchLastLogin(ctx context.Context, id uint, loginAt time.Time) error
+}
+
+type linkedIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewLinkedIdentityRepository(db *gorm.DB) LinkedIdentityRepository {
+	return &linkedIdentityRepository{db: db}
+}
+
+func (r *linkedIdentityRepository) Create(ctx context.Context, identity *entity.LinkedIdentity) error {
+	return conn(ctx, r.db).Create(identity).Error
+}
+
+func (r *linkedIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*entity.LinkedIdentity, error) {
+	var identity entity.LinkedIdentity
+	err := conn(ctx, r.db).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *linkedIdentityRepository) TouchLastLogin(ctx context.Context, id uint, loginAt time.Time) error {
+	return conn(ctx, r.db).Model(&entity.LinkedIdentity{}).
+		Where("id = ?", id).
+		Update("last_login_at", loginAt).Error
+}
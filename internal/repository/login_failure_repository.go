@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"echto/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginFailureRepository interface {
+	GetByKeys(ctx context.Context, keys ...string) ([]entity.LoginFailure, error)
+	Increment(ctx context.Context, key string, window time.Duration) (*entity.LoginFailure, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Decrement(ctx context.Context, key string) error
+	Delete(ctx context.Context, key string) error
+	DeleteStale(ctx context.Context, before time.Time) error
+}
+
+type loginFailureRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginFailureRepository(db *gorm.DB) LoginFailureRepository {
+	return &loginFailureRepository{db: db}
+}
+
+func (r *loginFailureRepository) GetByKeys(ctx context.Context, keys ...string) ([]entity.LoginFailure, error) {
+	var failures []entity.LoginFailure
+	err := conn(ctx, r.db).Where("key IN ?", keys).Find(&failures).Error
+	if err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// Increment atomically records a failure for the key. The counter starts over when
+// the previous failure is older than window.
+func (r *loginFailureRepository) Increment(ctx context.Context, key string, window time.Duration) (*entity.LoginFailure, error) {
+	now := time.Now()
+	failure := entity.LoginFailure{
+		Key:          key,
+		Failures:     1,
+		LastFailedAt: now,
+	}
+
+	err := conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":       gorm.Expr("CASE WHEN login_failures.last_failed_at < ? THEN 1 ELSE login_failures.failures + 1 END", now.Add(-window)),
+			"last_failed_at": now,
+		}),
+	}).Create(&failure).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn(ctx, r.db).Where("key = ?", key).First(&failure).Error; err != nil {
+		return nil, err
+	}
+	return &failure, nil
+}
+
+func (r *loginFailureRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	return conn(ctx, r.db).Model(&entity.LoginFailure{}).
+		Where("key = ?", key).
+		Update("locked_until", until).Error
+}
+
+// Decrement takes one failure off the counter of a key that is not locked
+func (r *loginFailureRepository) Decrement(ctx context.Context, key string) error {
+	return conn(ctx, r.db).Model(&entity.LoginFailure{}).
+		Where("key = ? AND failures > 0 AND (locked_until IS NULL OR locked_until < ?)", key, time.Now()).
+		Update("failures", gorm.Expr("failures - 1")).Error
+}
+
+func (r *loginFailureRepository) Delete(ctx context.Context, key string) error {
+	return conn(ctx, r.db).Where("key = ?", key).Delete(&entity.LoginFailure{}).Error
+}
+
+// DeleteStale removes counters whose last failure and lockout both ended before the given time
+func (r *loginFailureRepository) DeleteStale(ctx context.Context, before time.Time) error {
+	return conn(ctx, r.db).
+		Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&entity.LoginFailure{}).Error
+}
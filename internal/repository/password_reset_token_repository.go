@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"echto/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, resetToken *entity.PasswordResetToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	InvalidateByUser(ctx context.Context, userID uint) error
+}
+
+type passwordResetTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetTokenRepository(db *gorm.DB) PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{db: db}
+}
+
+func (r *passwordResetTokenRepository) Create(ctx context.Context, resetToken *entity.PasswordResetToken) error {
+	return conn(ctx, r.db).Create(resetToken).Error
+}
+
+func (r *passwordResetTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error) {
+	var resetToken entity.PasswordResetToken
+	err := conn(ctx, r.db).Where("token_hash = ?", tokenHash).First(&resetToken).Error
+	if err != nil {
+		return nil, err
+	}
+	return &resetToken, nil
+}
+
+// MarkUsed consumes the token. It reports false when the token had already been used.
+func (r *passwordResetTokenRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := conn(ctx, r.db).Model(&entity.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateByUser consumes every outstanding token of the user
+func (r *passwordResetTokenRepository) InvalidateByUser(ctx context.Context, userID uint) error {
+	return conn(ctx, r.db).Model(&entity.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
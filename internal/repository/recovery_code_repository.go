@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"echto/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	Replace(ctx context.Context, userID uint, codes []entity.RecoveryCode) error
+	Consume(ctx context.Context, userID uint, codeHash string) (bool, error)
+}
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+// Replace deletes the user's existing codes and stores the new set
+func (r *recoveryCodeRepository) Replace(ctx context.Context, userID uint, codes []entity.RecoveryCode) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Consume marks an unused code as used. It reports false when no such code exists.
+func (r *recoveryCodeRepository) Consume(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	result := conn(ctx, r.db).Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
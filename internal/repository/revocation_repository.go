@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"echto/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevocationRepository is a denylist of access tokens consulted on every authenticated request
+type RevocationRepository interface {
+	Revoke(ctx context.Context, jti string, userID uint, expiresAt time.Time) error
+	RevokeUser(ctx context.Context, userID uint, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string, userID, actorID uint, issuedAt time.Time) (bool, error)
+	DeleteExpired(ctx context.Context) error
+}
+
+type revocationRepository struct {
+	db *gorm.DB
+}
+
+func NewRevocationRepository(db *gorm.DB) RevocationRepository {
+	return &revocationRepository{db: db}
+}
+
+// Revoke denylists a single token until it expires
+func (r *revocationRepository) Revoke(ctx context.Context, jti string, userID uint, expiresAt time.Time) error {
+	return conn(ctx, r.db).Create(&entity.RevokedToken{
+		JTI:       &jti,
+		UserID:    userID,
+		RevokedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// RevokeUser denylists every token issued to the user up to now
+func (r *revocationRepository) RevokeUser(ctx context.Context, userID uint, expiresAt time.Time) error {
+	return conn(ctx, r.db).Create(&entity.RevokedToken{
+		UserID:    userID,
+		RevokedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// IsRevoked reports whether a token was denylisted by its ID or by a revocation of all
+// tokens of the user. An impersonation token is also revoked with the tokens of its actor,
+// so demoting or deleting an admin ends the impersonations they started. issuedAt only has
+// second precision, so a user revocation applies to tokens issued in earlier seconds;
+// those issued in the same second, such as the tokens of a login right after it, stay valid.
+func (r *revocationRepository) IsRevoked(ctx context.Context, jti string, userID, actorID uint, issuedAt time.Time) (bool, error) {
+	userIDs := []uint{userID}
+	if actorID != 0 {
+		userIDs = append(userIDs, actorID)
+	}
+
+	var count int64
+	err := conn(ctx, r.db).Model(&entity.RevokedToken{}).
+		Where("expires_at > ?", time.Now()).
+		Where(r.db.Where("jti = ?", jti).
+			Or("jti IS NULL AND user_id IN ? AND revoked_at >= ?", userIDs, nextSecond(issuedAt))).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *revocationRepository) DeleteExpired(ctx context.Context) error {
+	return conn(ctx, r.db).Where("expires_at <= ?", time.Now()).Delete(&entity.RevokedToken{}).Error
+}
+
+// nextSecond returns the start of the second after t, the earliest revocation time that
+// covers a token issued at t
+func nextSecond(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(time.Second)
+}
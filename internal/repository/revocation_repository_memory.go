@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryRevocation struct {
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+type memoryRevocationRepository struct {
+	mu    sync.RWMutex
+	jtis  map[string]memoryRevocation
+	users map[uint][]memoryRevocation
+}
+
+// NewMemoryRevocationRepository returns a process-local RevocationRepository, intended for tests
+func NewMemoryRevocationRepository() RevocationRepository {
+	return &memoryRevocationRepository{
+		jtis:  make(map[string]memoryRevocation),
+		users: make(map[uint][]memoryRevocation),
+	}
+}
+
+func (r *memoryRevocationRepository) Revoke(ctx context.Context, jti string, userID uint, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.jtis[jti] = memoryRevocation{revokedAt: time.Now(), expiresAt: expiresAt}
+	return nil
+}
+
+func (r *memoryRevocationRepository) RevokeUser(ctx context.Context, userID uint, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[userID] = append(r.users[userID], memoryRevocation{revokedAt: time.Now(), expiresAt: expiresAt})
+	return nil
+}
+
+func (r *memoryRevocationRepository) IsRevoked(ctx context.Context, jti string, userID, actorID uint, issuedAt time.Time) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	if entry, ok := r.jtis[jti]; ok && entry.expiresAt.After(now) {
+		return true, nil
+	}
+	userIDs := []uint{userID}
+	if actorID != 0 {
+		userIDs = append(userIDs, actorID)
+	}
+	for _, id := range userIDs {
+		for _, entry := range r.users[id] {
+			if entry.expiresAt.After(now) && !entry.revokedAt.Before(nextSecond(issuedAt)) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRevocationRepository) DeleteExpired(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for jti, entry := range r.jtis {
+		if !entry.expiresAt.After(now) {
+			delete(r.jtis, jti)
+		}
+	}
+	for userID, entries := range r.users {
+		active := entries[:0]
+		for _, entry := range entries {
+			if entry.expiresAt.After(now) {
+				active = append(active, entry)
+			}
+		}
+		if len(active) == 0 {
+			delete(r.users, userID)
+		} else {
+			r.users[userID] = active
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"echto/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *entity.Session) error
+	GetByID(ctx context.Context, id uint) (*entity.Session, error)
+	GetByFamilyID(ctx context.Context, familyID string) (*entity.Session, error)
+	GetByUser(ctx context.Context, userID uint) ([]entity.Session, error)
+	Touch(ctx context.Context, id uint, ipAddress string, seenAt time.Time) error
+	Delete(ctx context.Context, id uint) error
+	DeleteByFamilyID(ctx context.Context, familyID string) error
+	DeleteByUser(ctx context.Context, userID uint) error
+	DeleteInactive(ctx context.Context, before time.Time) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *entity.Session) error {
+	return conn(ctx, r.db).Create(session).Error
+}
+
+func (r *sessionRepository) GetByID(ctx context.Context, id uint) (*entity.Session, error) {
+	var session entity.Session
+	err := conn(ctx, r.db).First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) GetByFamilyID(ctx context.Context, familyID string) (*entity.Session, error) {
+	var session entity.Session
+	err := conn(ctx, r.db).Where("family_id = ?", familyID).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByUser returns the sessions of a user, most recently seen first
+func (r *sessionRepository) GetByUser(ctx context.Context, userID uint) ([]entity.Session, error) {
+	var sessions []entity.Session
+	err := conn(ctx, r.db).
+		Where("user_id = ?", userID).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, id uint, ipAddress string, seenAt time.Time) error {
+	return conn(ctx, r.db).Model(&entity.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"ip_address":   ipAddress,
+			"last_seen_at": seenAt,
+		}).Error
+}
+
+func (r *sessionRepository) Delete(ctx context.Context, id uint) error {
+	return conn(ctx, r.db).Delete(&entity.Session{}, id).Error
+}
+
+func (r *sessionRepository) DeleteByFamilyID(ctx context.Context, familyID string) error {
+	return conn(ctx, r.db).Where("family_id = ?", familyID).Delete(&entity.Session{}).Error
+}
+
+func (r *sessionRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	return conn(ctx, r.db).Where("user_id = ?", userID).Delete(&entity.Session{}).Error
+}
+
+// DeleteInactive removes sessions not seen since before, whose refresh tokens have expired
+func (r *sessionRepository) DeleteInactive(ctx context.Context, before time.Time) error {
+	return conn(ctx, r.db).Where("last_seen_at < ?", before).Delete(&entity.Session{}).Error
+}
@@ -1,73 +1,344 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"echto/internal/entity"
+	"errors"
+	"strings"
+	"sync"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
-	Create(user *entity.User) error
-	GetByID(id uint) (*entity.User, error)
-	GetByEmail(email string) (*entity.User, error)
-	GetAll(page, limit int) ([]entity.User, int64, error)
-	Update(user *entity.User) error
-	Delete(id uint) error
+	Create(ctx context.Context, user *entity.User) error
+	GetByID(ctx context.Context, id uint) (*entity.User, error)
+	GetByEmail(ctx context.Context, email string) (*entity.User, error)
+	GetAll(ctx context.Context, filter UserFilter, page, limit int) ([]entity.User, error)
+	GetAllByKeyset(ctx context.Context, filter UserFilter, keyset *Keyset, limit int) ([]entity.User, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+	Search(ctx context.Context, query string, limit int) ([]UserMatch, error)
+	Update(ctx context.Context, user *entity.User) error
+	UpdateTOTPStep(ctx context.Context, id uint, step int64) (bool, error)
+	UpdatePasswordHash(ctx context.Context, id uint, oldHash, newHash string) (bool, error)
+	Delete(ctx context.Context, id uint, version uint) error
+}
+
+// ErrStaleVersion is returned when a user was changed since it was read
+var ErrStaleVersion = errors.New("stale user version")
+
+// UserFilter narrows and orders the users returned by GetAll. Empty fields are ignored.
+type UserFilter struct {
+	// Query matches users whose name or email contains it
+	Query        string
+	NameContains string
+	EmailDomain  string
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	// Sort lists the columns to order by; the ID always breaks ties
+	Sort []SortField
+}
+
+// SortField orders results by a column. Column must come from a whitelist, never
+// from user input.
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// Keyset positions a page after the row with the given sort values and ID, or before
+// it when Backward is set. Values holds one value per UserFilter.Sort column.
+type Keyset struct {
+	Values   []interface{}
+	ID       uint
+	Backward bool
+}
+
+// UserMatch is a user found by Search with its relevance, higher being better
+type UserMatch struct {
+	entity.User
+	Score float64
 }
 
 type userRepository struct {
 	db *gorm.DB
+
+	// fullText caches whether full-text search is available. It is checked again after
+	// fullTextRecheck, so applying or rolling back migration 012 takes effect without a
+	// restart. now and probeFullText are replaced by tests.
+	mu                sync.Mutex
+	fullText          bool
+	fullTextCheckedAt time.Time
+	fullTextRecheck   time.Duration
+	now               func() time.Time
+	probeFullText     func(ctx context.Context) (bool, error)
 }
 
+// fullTextRecheckInterval is how long search keeps to one kind of matching before
+// checking again whether full-text search is available
+const fullTextRecheckInterval = time.Minute
+
 func NewUserRepository(db *gorm.DB) UserRepository {
-	return &userRepository{db: db}
+	r := &userRepository{db: db, fullTextRecheck: fullTextRecheckInterval, now: time.Now}
+	r.probeFullText = r.fullTextInstalled
+	return r
 }
 
-func (r *userRepository) Create(user *entity.User) error {
-	return r.db.Create(user).Error
+func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
+	return conn(ctx, r.db).Create(user).Error
 }
 
-func (r *userRepository) GetByID(id uint) (*entity.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id uint) (*entity.User, error) {
 	var user entity.User
-	err := r.db.First(&user, id).Error
+	err := conn(ctx, r.db).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) GetByEmail(email string) (*entity.User, error) {
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
 	var user entity.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := conn(ctx, r.db).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) GetAll(page, limit int) ([]entity.User, int64, error) {
+func (r *userRepository) GetAll(ctx context.Context, filter UserFilter, page, limit int) ([]entity.User, error) {
 	var users []entity.User
+	offset := (page - 1) * limit
+	err := conn(ctx, r.db).Scopes(filterUsers(filter)).Clauses(orderBy(filter.Sort, false)).Offset(offset).Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetAllByKeyset returns up to limit users following the keyset in the sort order,
+// or the first users when keyset is nil. Backward pages are returned in sort order too.
+// Unlike GetAll it never skips rows, so its cost does not grow with the position.
+func (r *userRepository) GetAllByKeyset(ctx context.Context, filter UserFilter, keyset *Keyset, limit int) ([]entity.User, error) {
+	var users []entity.User
+	backward := keyset != nil && keyset.Backward
+
+	query := conn(ctx, r.db).Scopes(filterUsers(filter))
+	if keyset != nil {
+		query = query.Where(keysetCondition(filter.Sort, keyset))
+	}
+	if err := query.Clauses(orderBy(filter.Sort, backward)).Limit(limit).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	if backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+	return users, nil
+}
+
+// Count returns the number of users matching the filter
+func (r *userRepository) Count(ctx context.Context, filter UserFilter) (int64, error) {
 	var total int64
+	err := conn(ctx, r.db).Model(&entity.User{}).Scopes(filterUsers(filter)).Count(&total).Error
+	return total, err
+}
 
-	// Count total records
-	if err := r.db.Model(&entity.User{}).Count(&total).Error; err != nil {
-		return nil, 0, err
+// Search returns the users whose name or email best match a free-text query. It ranks
+// by full-text and trigram similarity when migration 012 and pg_trgm are in place, so
+// misspelled names are found too, and falls back to substring matching otherwise.
+func (r *userRepository) Search(ctx context.Context, query string, limit int) ([]UserMatch, error) {
+	fullText := r.fullTextAvailable(ctx)
+
+	var matches []UserMatch
+	db := conn(ctx, r.db).Model(&entity.User{})
+	if fullText {
+		db = db.
+			Select("users.*, ts_rank(search_vector, websearch_to_tsquery('simple', @q)) + GREATEST(word_similarity(@q, name), word_similarity(@q, email)) AS score", sql.Named("q", query)).
+			Where("search_vector @@ websearch_to_tsquery('simple', @q) OR @q <% name OR @q <% email", sql.Named("q", query))
+	} else {
+		prefix, contains := escapeLike(query)+"%", "%"+escapeLike(query)+"%"
+		db = db.
+			Select("users.*, CASE WHEN name ILIKE @prefix OR email ILIKE @prefix THEN 1.0 ELSE 0.5 END AS score", sql.Named("prefix", prefix)).
+			Where("name ILIKE @contains OR email ILIKE @contains", sql.Named("contains", contains))
 	}
 
-	// Get paginated records
-	offset := (page - 1) * limit
-	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
-	if err != nil {
-		return nil, 0, err
+	if err := db.Order("score DESC, id").Limit(limit).Scan(&matches).Error; err != nil {
+		return nil, err
 	}
+	return matches, nil
+}
+
+// fullTextAvailable reports whether full-text search can be used, probing the database
+// at most once per recheck interval
+func (r *userRepository) fullTextAvailable(ctx context.Context) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	return users, total, nil
+	now := r.now()
+	if !r.fullTextCheckedAt.IsZero() && now.Sub(r.fullTextCheckedAt) < r.fullTextRecheck {
+		return r.fullText
+	}
+
+	// A failed probe falls back to substring matching, which works on every schema; if the
+	// database itself is failing, the search query reports it
+	available, err := r.probeFullText(ctx)
+	r.fullText = err == nil && available
+	r.fullTextCheckedAt = now
+	return r.fullText
+}
+
+// fullTextInstalled reports whether the pg_trgm extension and the search_vector column
+// added by migration 012 exist. Databases set up by AutoMigrate alone lack the column.
+func (r *userRepository) fullTextInstalled(ctx context.Context) (bool, error) {
+	var available bool
+	err := conn(ctx, r.db).Raw(`SELECT
+		EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') AND
+		EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'search_vector')`).
+		Scan(&available).Error
+	return available, err
+}
+
+// Update saves a user and increments its version, provided the stored version is still
+// the one the user was read with. Otherwise nothing is saved and ErrStaleVersion is returned.
+func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
+	version := user.Version
+	user.Version++
+
+	result := conn(ctx, r.db).Model(user).Where("version = ?", version).Select("*").Updates(user)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrStaleVersion
+	}
+	if result.Error != nil {
+		user.Version = version
+		return result.Error
+	}
+	return nil
+}
+
+// UpdateTOTPStep records the last accepted TOTP time step. It reports false when the
+// step is not newer than the stored one, i.e. the code was already used. The version is
+// incremented, so a full Update from an earlier read cannot write back the old step.
+func (r *userRepository) UpdateTOTPStep(ctx context.Context, id uint, step int64) (bool, error) {
+	result := conn(ctx, r.db).Model(&entity.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Updates(map[string]interface{}{
+			"totp_last_step": step,
+			"version":        gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UpdatePasswordHash replaces the stored hash of an unchanged password. It reports false
+// when the password was changed in the meantime. Like UpdateTOTPStep it increments the version.
+func (r *userRepository) UpdatePasswordHash(ctx context.Context, id uint, oldHash, newHash string) (bool, error) {
+	result := conn(ctx, r.db).Model(&entity.User{}).
+		Where("id = ? AND password = ?", id, oldHash).
+		Updates(map[string]interface{}{
+			"password": newHash,
+			"version":  gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Delete deletes a user that still has the given version, returning ErrStaleVersion
+// when it was changed in the meantime
+func (r *userRepository) Delete(ctx context.Context, id uint, version uint) error {
+	result := conn(ctx, r.db).Where("version = ?", version).Delete(&entity.User{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaleVersion
+	}
+	return nil
+}
+
+// filterUsers applies the conditions of a UserFilter. Text is matched case-insensitively
+// with its LIKE wildcards escaped, so it is always taken literally.
+func filterUsers(filter UserFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Query != "" {
+			pattern := "%" + escapeLike(filter.Query) + "%"
+			db = db.Where("name ILIKE ? OR email ILIKE ?", pattern, pattern)
+		}
+		if filter.NameContains != "" {
+			db = db.Where("name ILIKE ?", "%"+escapeLike(filter.NameContains)+"%")
+		}
+		if filter.EmailDomain != "" {
+			db = db.Where("email ILIKE ?", "%@"+escapeLike(filter.EmailDomain))
+		}
+		if filter.CreatedFrom != nil {
+			db = db.Where("created_at >= ?", *filter.CreatedFrom)
+		}
+		if filter.CreatedTo != nil {
+			db = db.Where("created_at < ?", *filter.CreatedTo)
+		}
+		return db
+	}
+}
+
+// orderBy builds the ORDER BY clause for the sort fields, ending with the ID so
+// that pages are stable. reverse flips every direction, for reading backwards.
+func orderBy(sort []SortField, reverse bool) clause.OrderBy {
+	keys := sortKeys(sort)
+	columns := make([]clause.OrderByColumn, len(keys))
+	for i, key := range keys {
+		columns[i] = clause.OrderByColumn{Column: clause.Column{Name: key.Column}, Desc: key.Desc != reverse}
+	}
+	return clause.OrderBy{Columns: columns}
+}
+
+// keysetCondition matches the rows after the keyset in the sort order, or before it
+// when reading backwards: (a > ?) OR (a = ? AND b > ?) OR ... with the ID last. An
+// expanded condition is needed because the columns may be sorted in different directions.
+func keysetCondition(sort []SortField, keyset *Keyset) clause.Expression {
+	keys := sortKeys(sort)
+	values := append(append([]interface{}{}, keyset.Values...), keyset.ID)
+
+	alternatives := make([]clause.Expression, len(keys))
+	for i, key := range keys {
+		conditions := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, clause.Eq{Column: clause.Column{Name: keys[j].Column}, Value: values[j]})
+		}
+
+		column := clause.Column{Name: key.Column}
+		if key.Desc != keyset.Backward {
+			conditions = append(conditions, clause.Lt{Column: column, Value: values[i]})
+		} else {
+			conditions = append(conditions, clause.Gt{Column: column, Value: values[i]})
+		}
+		alternatives[i] = clause.And(conditions...)
+	}
+	return clause.Or(alternatives...)
 }
 
-func (r *userRepository) Update(user *entity.User) error {
-	return r.db.Save(user).Error
+// sortKeys returns the sort fields followed by the ID, unless they already sort by it
+func sortKeys(sort []SortField) []SortField {
+	for _, field := range sort {
+		if field.Column == "id" {
+			return sort
+		}
+	}
+	return append(append([]SortField{}, sort...), SortField{Column: "id"})
 }
 
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&entity.User{}, id).Error
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes s match itself literally inside a LIKE pattern
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
 }
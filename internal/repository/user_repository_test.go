@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// statementLogger records the SQL of every statement instead of logging it
+type statementLogger struct {
+	logger.Interface
+	statements []string
+}
+
+func (l *statementLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *statementLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	l.statements = append(l.statements, sql)
+}
+
+// dryRunDB returns a connection that builds statements without a database
+func dryRunDB(t *testing.T) (*gorm.DB, *statementLogger) {
+	statements := &statementLogger{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 statements,
+	})
+	require.NoError(t, err)
+	return db, statements
+}
+
+// Writes made while signing in must increment the version like any other update
+func TestUserRepository_SignInWritesIncrementVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(r UserRepository) error
+	}{
+		{
+			name: "totp step",
+			write: func(r UserRepository) error {
+				_, err := r.UpdateTOTPStep(context.Background(), 1, 42)
+				return err
+			},
+		},
+		{
+			name: "password hash",
+			write: func(r UserRepository) error {
+				_, err := r.UpdatePasswordHash(context.Background(), 1, "old-hash", "new-hash")
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, statements := dryRunDB(t)
+
+			require.NoError(t, tt.write(NewUserRepository(db)))
+			require.Len(t, statements.statements, 1)
+			assert.Contains(t, statements.statements[0], `"version"=version + 1`)
+		})
+	}
+}
+
+// Search keeps to the kind of matching last probed for until the recheck interval passes
+func TestUserRepository_Search_RechecksFullText(t *testing.T) {
+	type probe struct {
+		available bool
+		err       error
+	}
+
+	steps := []struct {
+		name             string
+		elapsed          time.Duration
+		probe            *probe
+		expectedFullText bool
+	}{
+		{name: "first search probes", elapsed: 0, probe: &probe{available: true}, expectedFullText: true},
+		{name: "within the interval", elapsed: 30 * time.Second, expectedFullText: true},
+		{name: "failed probe falls back", elapsed: 61 * time.Second, probe: &probe{err: errors.New("connection reset")}, expectedFullText: false},
+		{name: "fallback is kept within the interval", elapsed: 90 * time.Second, expectedFullText: false},
+		{name: "migration not applied", elapsed: 122 * time.Second, probe: &probe{available: false}, expectedFullText: false},
+		{name: "successful probe switches back", elapsed: 183 * time.Second, probe: &probe{available: true}, expectedFullText: true},
+	}
+
+	db, statements := dryRunDB(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	var next *probe
+	probes := 0
+
+	r := NewUserRepository(db).(*userRepository)
+	r.fullTextRecheck = time.Minute
+	r.now = func() time.Time { return now }
+	r.probeFullText = func(ctx context.Context) (bool, error) {
+		probes++
+		require.NotNil(t, next, "unexpected probe")
+		return next.available, next.err
+	}
+
+	for _, step := range steps {
+		now = start.Add(step.elapsed)
+		next = step.probe
+		before := probes
+
+		// A dry run cannot scan results, but the statement is built and recorded
+		_, _ = r.Search(context.Background(), "john", 10)
+
+		if step.probe != nil {
+			assert.Equal(t, before+1, probes, step.name)
+		} else {
+			assert.Equal(t, before, probes, step.name)
+		}
+		sql := statements.statements[len(statements.statements)-1]
+		if step.expectedFullText {
+			assert.Contains(t, sql, "websearch_to_tsquery", step.name)
+		} else {
+			assert.Contains(t, sql, "ILIKE", step.name)
+		}
+	}
+}
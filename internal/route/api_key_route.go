@@ -0,0 +1,28 @@
+package route
+
+import (
+	"echto/internal/entity"
+	"echto/internal/handler"
+	echtoMiddleware "echto/pkg/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func APIKeyRoute(e *echo.Echo, apiKeyHandler *handler.APIKeyHandler, auth echo.MiddlewareFunc) {
+	// Policies
+	selfOnly := echtoMiddleware.Authorize(echtoMiddleware.IsSelf("id"))
+	selfOrAdmin := echtoMiddleware.Authorize(echtoMiddleware.IsSelf("id"), echtoMiddleware.HasRole(entity.RoleAdmin))
+
+	// Operations refused to impersonation tokens
+	notImpersonating := echtoMiddleware.DenyImpersonation()
+
+	api := e.Group("/api/v1")
+	{
+		apiKeys := api.Group("/users/:id/api-keys", auth)
+		{
+			apiKeys.POST("", apiKeyHandler.CreateAPIKey, notImpersonating, selfOnly)
+			apiKeys.GET("", apiKeyHandler.GetAPIKeys, selfOrAdmin)
+			apiKeys.DELETE("/:keyId", apiKeyHandler.RevokeAPIKey, selfOrAdmin)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package route
+
+import (
+	"echto/internal/entity"
+	"echto/internal/handler"
+	echtoMiddleware "echto/pkg/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func AuthRoute(e *echo.Echo, authHandler *handler.AuthHandler, auth echo.MiddlewareFunc) {
+	// Policies
+	selfOnly := echtoMiddleware.Authorize(echtoMiddleware.IsSelf("id"))
+	selfOrAdmin := echtoMiddleware.Authorize(echtoMiddleware.IsSelf("id"), echtoMiddleware.HasRole(entity.RoleAdmin))
+	adminOnly := echtoMiddleware.Authorize(echtoMiddleware.HasRole(entity.RoleAdmin))
+
+	// Operations refused to impersonation tokens
+	notImpersonating := echtoMiddleware.DenyImpersonation()
+
+	api := e.Group("/api/v1")
+	{
+		authGroup := api.Group("/auth")
+		{
+			authGroup.POST("/login", authHandler.Login)
+			authGroup.POST("/2fa/verify", authHandler.VerifyTwoFactor)
+			authGroup.POST("/refresh", authHandler.Refresh)
+			authGroup.POST("/logout", authHandler.Logout, auth)
+		}
+
+		users := api.Group("/users")
+		{
+			users.PUT("/:id/password", authHandler.ChangePassword, auth, notImpersonating, selfOnly)
+			users.DELETE("/:id/sessions", authHandler.RevokeUserSessions, auth, notImpersonating, selfOrAdmin)
+			users.POST("/:id/unlock", authHandler.UnlockUser, auth, adminOnly)
+			users.POST("/:id/impersonate", authHandler.Impersonate, auth, notImpersonating, adminOnly)
+		}
+	}
+}
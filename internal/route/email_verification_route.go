@@ -0,0 +1,18 @@
+package route
+
+import (
+	"echto/internal/handler"
+
+	"github.com/labstack/echo/v4"
+)
+
+func EmailVerificationRoute(e *echo.Echo, emailVerificationHandler *handler.EmailVerificationHandler) {
+	api := e.Group("/api/v1")
+	{
+		auth := api.Group("/auth")
+		{
+			auth.POST("/verify-email", emailVerificationHandler.ConfirmEmail)
+			auth.POST("/verify-email/resend", emailVerificationHandler.ResendVerification)
+		}
+	}
+}
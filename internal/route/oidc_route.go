@@ -0,0 +1,18 @@
+package route
+
+import (
+	"echto/internal/handler"
+
+	"github.com/labstack/echo/v4"
+)
+
+func OIDCRoute(e *echo.Echo, oidcHandler *handler.OIDCHandler) {
+	api := e.Group("/api/v1")
+	{
+		oidc := api.Group("/auth/oidc")
+		{
+			oidc.GET("/:provider/login", oidcHandler.Login)
+			oidc.GET("/:provider/callback", oidcHandler.Callback)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package route
+
+import (
+	"echto/internal/handler"
+
+	"github.com/labstack/echo/v4"
+)
+
+func PasswordResetRoute(e *echo.Echo, passwordResetHandler *handler.PasswordResetHandler) {
+	api := e.Group("/api/v1")
+	{
+		auth := api.Group("/auth")
+		{
+			auth.POST("/password-reset", passwordResetHandler.RequestReset)
+			auth.POST("/password-reset/confirm", passwordResetHandler.ConfirmReset)
+		}
+	}
+}
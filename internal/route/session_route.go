@@ -0,0 +1,22 @@
+package route
+
+import (
+	"echto/internal/handler"
+	echtoMiddleware "echto/pkg/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func SessionRoute(e *echo.Echo, sessionHandler *handler.SessionHandler, auth echo.MiddlewareFunc) {
+	// Operations refused to impersonation tokens
+	notImpersonating := echtoMiddleware.DenyImpersonation()
+
+	api := e.Group("/api/v1")
+	{
+		sessions := api.Group("/users/me/sessions", auth)
+		{
+			sessions.GET("", sessionHandler.GetSessions)
+			sessions.DELETE("/:sessionId", sessionHandler.RevokeSession, notImpersonating)
+		}
+	}
+}
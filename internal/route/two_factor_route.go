@@ -0,0 +1,22 @@
+package route
+
+import (
+	"echto/internal/handler"
+	echtoMiddleware "echto/pkg/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TwoFactorRoute(e *echo.Echo, twoFactorHandler *handler.TwoFactorHandler, auth echo.MiddlewareFunc) {
+	// Operations refused to impersonation tokens
+	notImpersonating := echtoMiddleware.DenyImpersonation()
+
+	api := e.Group("/api/v1")
+	{
+		twoFactor := api.Group("/auth/2fa", auth, notImpersonating)
+		{
+			twoFactor.POST("/enroll", twoFactorHandler.Enroll)
+			twoFactor.POST("/confirm", twoFactorHandler.Confirm)
+		}
+	}
+}
@@ -1,28 +1,45 @@
 package route
 
 import (
+	"echto/internal/entity"
 	"echto/internal/handler"
+	echtoMiddleware "echto/pkg/middleware"
 
 	"github.com/labstack/echo/v4"
 )
 
-func UserRoute(e *echo.Echo, userHandler *handler.UserHandler) {
-	e.GET("/users", userHandler.GetUsers)
-	e.GET("/users/:id", userHandler.GetUser)
+func UserRoute(e *echo.Echo, userHandler *handler.UserHandler, auth echo.MiddlewareFunc) {
+	// Policies
+	adminOnly := echtoMiddleware.Authorize(echtoMiddleware.HasRole(entity.RoleAdmin))
+	selfOrAdmin := echtoMiddleware.Authorize(echtoMiddleware.IsSelf("id"), echtoMiddleware.HasRole(entity.RoleAdmin))
+
+	// Scopes required from API keys
+	canRead := echtoMiddleware.RequireScope(entity.ScopeUsersRead)
+	canWrite := echtoMiddleware.RequireScope(entity.ScopeUsersWrite)
+
+	// Operations refused to impersonation tokens
+	notImpersonating := echtoMiddleware.DenyImpersonation()
+
+	e.GET("/users", userHandler.GetUsers, auth, canRead, adminOnly)
+	e.GET("/users/:id", userHandler.GetUser, auth, canRead, selfOrAdmin)
 	e.POST("/users", userHandler.CreateUser)
-	e.PUT("/users/:id", userHandler.UpdateUser)
-	e.DELETE("/users/:id", userHandler.DeleteUser)
+	e.PUT("/users/:id", userHandler.UpdateUser, auth, canWrite, notImpersonating, selfOrAdmin)
+	e.PATCH("/users/:id", userHandler.PatchUser, auth, canWrite, notImpersonating, selfOrAdmin)
+	e.DELETE("/users/:id", userHandler.DeleteUser, auth, canWrite, notImpersonating, adminOnly)
 
 	// Routes
 	api := e.Group("/api/v1")
 	{
 		users := api.Group("/users")
 		{
-			users.GET("", userHandler.GetUsers)
-			users.GET("/:id", userHandler.GetUser)
+			users.GET("", userHandler.GetUsers, auth, canRead, adminOnly)
+			users.GET("/search", userHandler.SearchUsers, auth, canRead, adminOnly)
+			users.GET("/:id", userHandler.GetUser, auth, canRead, selfOrAdmin)
 			users.POST("", userHandler.CreateUser)
-			users.PUT("/:id", userHandler.UpdateUser)
-			users.DELETE("/:id", userHandler.DeleteUser)
+			users.PUT("/:id", userHandler.UpdateUser, auth, canWrite, notImpersonating, selfOrAdmin)
+			users.PATCH("/:id", userHandler.PatchUser, auth, canWrite, notImpersonating, selfOrAdmin)
+			users.PUT("/:id/role", userHandler.UpdateUserRole, auth, canWrite, adminOnly)
+			users.DELETE("/:id", userHandler.DeleteUser, auth, canWrite, notImpersonating, adminOnly)
 		}
 	}
 
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/internal/repository"
+	"echto/pkg/logger"
+	"echto/pkg/token"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyTouchInterval limits how often the last-used timestamp of a key is written
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID uint, req *model.APIKeyCreateRequest) (*model.APIKeyCreateResponse, error)
+	GetAPIKeys(ctx context.Context, userID uint) (*model.APIKeyListResponse, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID uint) error
+	Authenticate(ctx context.Context, rawKey string) (*token.Claims, bool, error)
+}
+
+type apiKeyService struct {
+	userRepo   repository.UserRepository
+	apiKeyRepo repository.APIKeyRepository
+}
+
+func NewAPIKeyService(userRepo repository.UserRepository, apiKeyRepo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyService{
+		userRepo:   userRepo,
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID uint, req *model.APIKeyCreateRequest) (*model.APIKeyCreateResponse, error) {
+	// Check if user exists
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	raw, prefix, keyHash, err := token.NewAPIKey()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate API key")
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	apiKey := &entity.APIKey{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: keyHash,
+		Scopes:  req.Scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := s.apiKeyRepo.Create(ctx, apiKey); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to store API key")
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return &model.APIKeyCreateResponse{
+		APIKeyResponse: *toAPIKeyResponse(apiKey),
+		Key:            raw,
+	}, nil
+}
+
+func (s *apiKeyService) GetAPIKeys(ctx context.Context, userID uint) (*model.APIKeyListResponse, error) {
+	// Check if user exists
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	apiKeys, err := s.apiKeyRepo.GetActiveByUser(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get API keys")
+		return nil, fmt.Errorf("failed to get api keys: %w", err)
+	}
+
+	responses := make([]model.APIKeyResponse, len(apiKeys))
+	for i := range apiKeys {
+		responses[i] = *toAPIKeyResponse(&apiKeys[i])
+	}
+
+	return &model.APIKeyListResponse{APIKeys: responses}, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, keyID uint) error {
+	revoked, err := s.apiKeyRepo.Revoke(ctx, userID, keyID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to revoke API key")
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// Authenticate resolves an API key to claims carrying the current role of its owner
+// and the scopes of the key. It reports false for unknown, revoked or expired keys.
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*token.Claims, bool, error) {
+	apiKey, err := s.apiKeyRepo.GetByHash(ctx, token.Hash(rawKey))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt)) {
+		return nil, false, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	// Recording usage is best effort and throttled to keep writes off the hot path
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.apiKeyRepo.TouchLastUsed(ctx, apiKey.ID, now); err != nil {
+			logger.Log.Warn().Err(err).Uint("api_key_id", apiKey.ID).Msg("Failed to record API key usage")
+		}
+	}
+
+	return &token.Claims{
+		UserID:   user.ID,
+		Role:     user.Role,
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.Scopes,
+	}, true, nil
+}
+
+// toAPIKeyResponse maps an API key entity to its public representation
+func toAPIKeyResponse(apiKey *entity.APIKey) *model.APIKeyResponse {
+	return &model.APIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		LastUsedAt: apiKey.LastUsedAt,
+		ExpiresAt:  apiKey.ExpiresAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
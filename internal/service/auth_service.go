@@ -0,0 +1,572 @@
+package service
+
+import (
+	"context"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/internal/repository"
+	"echto/pkg/logger"
+	"echto/pkg/password"
+	"echto/pkg/token"
+	"echto/pkg/useragent"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AuthService interface {
+	Login(ctx context.Context, req *model.LoginRequest, client ClientInfo) (*model.LoginResponse, error)
+	LoginUser(ctx context.Context, user *entity.User, client ClientInfo) (*model.LoginResponse, error)
+	VerifyTwoFactor(ctx context.Context, req *model.TwoFactorVerifyRequest, client ClientInfo) (*model.TokenResponse, error)
+	Refresh(ctx context.Context, req *model.RefreshRequest, client ClientInfo) (*model.TokenResponse, error)
+	Logout(ctx context.Context, claims *token.Claims, req *model.LogoutRequest) error
+	RevokeUserSessions(ctx context.Context, userID uint) error
+	RevokeAllTokens(ctx context.Context, userID uint) error
+	UnlockUser(ctx context.Context, userID uint) error
+	Impersonate(ctx context.Context, actorID, userID uint) (*model.ImpersonationResponse, error)
+	ChangePassword(ctx context.Context, userID uint, req *model.PasswordChangeRequest) error
+}
+
+// AuthServiceConfig holds the settings of AuthService
+type AuthServiceConfig struct {
+	// RefreshTTL is the lifetime of issued refresh tokens
+	RefreshTTL time.Duration
+	// RequireVerifiedEmail rejects logins from users who have not verified their email
+	RequireVerifiedEmail bool
+	// ImpersonationTTL is the lifetime of tokens issued to admins acting as another user
+	ImpersonationTTL time.Duration
+}
+
+type authService struct {
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	revocationRepo   repository.RevocationRepository
+	sessionRepo      repository.SessionRepository
+	twoFactorService TwoFactorService
+	loginThrottle    LoginThrottleService
+	hasher           *password.Hasher
+	passwordPolicy   *password.Policy
+	tokens           *token.Manager
+	config           AuthServiceConfig
+}
+
+func NewAuthService(userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository, revocationRepo repository.RevocationRepository, sessionRepo repository.SessionRepository, twoFactorService TwoFactorService, loginThrottle LoginThrottleService, hasher *password.Hasher, passwordPolicy *password.Policy, tokens *token.Manager, config AuthServiceConfig) AuthService {
+	return &authService{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revocationRepo:   revocationRepo,
+		sessionRepo:      sessionRepo,
+		twoFactorService: twoFactorService,
+		loginThrottle:    loginThrottle,
+		hasher:           hasher,
+		passwordPolicy:   passwordPolicy,
+		tokens:           tokens,
+		config:           config,
+	}
+}
+
+func (s *authService) Login(ctx context.Context, req *model.LoginRequest, client ClientInfo) (*model.LoginResponse, error) {
+	// Refuse attempts while the account or client is backing off
+	if err := s.checkThrottle(ctx, req.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	// Look up user by email
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Take as long as a wrong password would, so the response time does not reveal the account is unknown
+			s.hasher.VerifyDummy(req.Password)
+			return nil, s.loginFailed(ctx, req.Email, client.IPAddress, ErrInvalidCredentials)
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	// Verify password against the stored hash
+	valid, err := s.hasher.Verify(req.Password, user.Password)
+	if err != nil {
+		logger.Log.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to verify password")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+	if !valid {
+		return nil, s.loginFailed(ctx, req.Email, client.IPAddress, ErrInvalidCredentials)
+	}
+
+	// Upgrade hashes made with an outdated algorithm or cost while the plain password is at hand
+	s.rehashPassword(ctx, user, req.Password)
+
+	return s.LoginUser(ctx, user, client)
+}
+
+// LoginUser finishes the login of an authenticated user, issuing either tokens or a
+// two-factor challenge. Callers must have verified the user's credentials.
+func (s *authService) LoginUser(ctx context.Context, user *entity.User, client ClientInfo) (*model.LoginResponse, error) {
+	// Optionally keep unverified accounts out
+	if s.config.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+
+	// Accounts with 2FA get a challenge instead of tokens
+	if user.TOTPEnabledAt != nil {
+		challengeToken, err := s.tokens.GenerateChallenge(user.ID)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to sign challenge token")
+			return nil, fmt.Errorf("failed to login: %w", err)
+		}
+		return &model.LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		}, nil
+	}
+
+	tokens, err := s.completeLogin(ctx, user, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	return &model.LoginResponse{TokenResponse: tokens}, nil
+}
+
+func (s *authService) VerifyTwoFactor(ctx context.Context, req *model.TwoFactorVerifyRequest, client ClientInfo) (*model.TokenResponse, error) {
+	claims, err := s.tokens.ParseChallenge(req.ChallengeToken)
+	if err != nil {
+		return nil, ErrInvalidChallengeToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidChallengeToken
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	// Code guesses count against the same counters as password guesses
+	if err := s.checkThrottle(ctx, user.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	valid, err := s.twoFactorService.VerifyCode(ctx, user, req.Code)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to verify two factor code")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+	if !valid {
+		return nil, s.loginFailed(ctx, user.Email, client.IPAddress, ErrInvalidTwoFactorCode)
+	}
+
+	tokens, err := s.completeLogin(ctx, user, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (s *authService) Refresh(ctx context.Context, req *model.RefreshRequest, client ClientInfo) (*model.TokenResponse, error) {
+	// Look up the presented token by its hash
+	current, err := s.refreshTokenRepo.GetByHash(ctx, token.Hash(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get refresh token")
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	if current.RevokedAt != nil || time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// A token that was already exchanged is being replayed, so the family is compromised
+	if current.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, current)
+	}
+
+	// Make sure the user still exists
+	user, err := s.userRepo.GetByID(ctx, current.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	// Consume the current token; losing this race also means it was replayed
+	rotated, err := s.refreshTokenRepo.MarkRotated(ctx, current.ID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to rotate refresh token")
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	if !rotated {
+		return nil, s.revokeReusedFamily(ctx, current)
+	}
+
+	if err := s.touchSession(ctx, user, current.FamilyID, client); err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	tokens, err := s.issueTokens(ctx, user, current.FamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func (s *authService) Logout(ctx context.Context, claims *token.Claims, req *model.LogoutRequest) error {
+	// Denylist the access token for the rest of its lifetime
+	if err := s.revocationRepo.Revoke(ctx, claims.Id, claims.UserID, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to revoke access token")
+		return fmt.Errorf("failed to logout: %w", err)
+	}
+
+	// End the session and its refresh token family as well. Access tokens name the family
+	// as their session; older ones without it rely on the client handing in the refresh token.
+	familyID := claims.SessionID
+	if familyID == "" && req.RefreshToken != "" {
+		refreshToken, err := s.refreshTokenRepo.GetByHash(ctx, token.Hash(req.RefreshToken))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Error().Err(err).Msg("Failed to get refresh token")
+			return fmt.Errorf("failed to logout: %w", err)
+		}
+		if err == nil && refreshToken.UserID == claims.UserID {
+			familyID = refreshToken.FamilyID
+		}
+	}
+	if familyID == "" {
+		return nil
+	}
+
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, familyID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to revoke refresh token family")
+		return fmt.Errorf("failed to logout: %w", err)
+	}
+	if err := s.sessionRepo.DeleteByFamilyID(ctx, familyID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to delete session")
+		return fmt.Errorf("failed to logout: %w", err)
+	}
+
+	return nil
+}
+
+func (s *authService) RevokeUserSessions(ctx context.Context, userID uint) error {
+	// Check if user exists
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.RevokeAllTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+func (s *authService) UnlockUser(ctx context.Context, userID uint) error {
+	// Get existing user
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.loginThrottle.Reset(ctx, user.Email); err != nil {
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+
+	return nil
+}
+
+func (s *authService) Impersonate(ctx context.Context, actorID, userID uint) (*model.ImpersonationResponse, error) {
+	if actorID == userID {
+		return nil, ErrCannotImpersonateSelf
+	}
+
+	// Get the user to act as
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Role == entity.RoleAdmin {
+		return nil, ErrCannotImpersonateAdmin
+	}
+
+	accessToken, claims, err := s.tokens.GenerateImpersonation(user.ID, user.Role, actorID, s.config.ImpersonationTTL)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to sign impersonation token")
+		return nil, fmt.Errorf("failed to impersonate user: %w", err)
+	}
+
+	// Audit trail
+	logger.Log.Warn().
+		Uint("actor_id", actorID).
+		Uint("user_id", user.ID).
+		Str("jti", claims.Id).
+		Time("expires_at", time.Unix(claims.ExpiresAt, 0)).
+		Msg("Impersonation token issued")
+
+	return &model.ImpersonationResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.config.ImpersonationTTL.Seconds()),
+		ActorID:     actorID,
+		SubjectID:   user.ID,
+	}, nil
+}
+
+func (s *authService) ChangePassword(ctx context.Context, userID uint, req *model.PasswordChangeRequest) error {
+	// Get existing user
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Guesses at the current password count against the account like failed logins, so a
+	// stolen access token cannot be used to find the password
+	if err := s.checkThrottle(ctx, user.Email, ""); err != nil {
+		return err
+	}
+
+	// Verify current password
+	valid, err := s.hasher.Verify(req.CurrentPassword, user.Password)
+	if err != nil {
+		logger.Log.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to verify password")
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+	if !valid {
+		return s.loginFailed(ctx, user.Email, "", ErrInvalidCurrentPassword)
+	}
+	if err := s.loginThrottle.RecordSuccess(ctx, user.Email, ""); err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+
+	// Check password strength
+	if err := checkPasswordPolicy(s.passwordPolicy, "new_password", req.NewPassword, user.Name, user.Email); err != nil {
+		return err
+	}
+
+	// Hash new password
+	hashedPassword, err := s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to hash password")
+		return fmt.Errorf("failed to process password: %w", err)
+	}
+
+	user.Password = hashedPassword
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to update password")
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+
+	// Sign out every session that was authenticated with the old password
+	if err := s.RevokeAllTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAllTokens denylists every access token issued to the user so far, including the
+// impersonation tokens an admin issued, and revokes all refresh tokens and sessions. Unlike
+// RevokeUserSessions it does not require the user to still exist, so it also cuts off users
+// that were just deleted.
+func (s *authService) RevokeAllTokens(ctx context.Context, userID uint) error {
+	// Access tokens issued so far expire within one access token lifetime, and the
+	// impersonation tokens the user issued as an admin within one impersonation lifetime
+	ttl := s.tokens.TTL()
+	if s.config.ImpersonationTTL > ttl {
+		ttl = s.config.ImpersonationTTL
+	}
+	if err := s.revocationRepo.RevokeUser(ctx, userID, time.Now().Add(ttl)); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to revoke access tokens")
+		return err
+	}
+
+	if err := s.refreshTokenRepo.RevokeByUser(ctx, userID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to revoke refresh tokens")
+		return err
+	}
+
+	if err := s.sessionRepo.DeleteByUser(ctx, userID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to delete sessions")
+		return err
+	}
+
+	return nil
+}
+
+// revokeReusedFamily revokes every token in the family of a replayed refresh token
+func (s *authService) revokeReusedFamily(ctx context.Context, replayed *entity.RefreshToken) error {
+	logger.Log.Warn().
+		Uint("user_id", replayed.UserID).
+		Str("family_id", replayed.FamilyID).
+		Msg("Refresh token reuse detected, revoking token family")
+
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, replayed.FamilyID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to revoke refresh token family")
+		return fmt.Errorf("failed to refresh token: %w", err)
+	}
+	if err := s.sessionRepo.DeleteByFamilyID(ctx, replayed.FamilyID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to delete session")
+		return fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// rehashPassword replaces an outdated password hash. Failures are logged and retried on the next login.
+func (s *authService) rehashPassword(ctx context.Context, user *entity.User, plain string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := s.hasher.Hash(plain)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to rehash password")
+		return
+	}
+
+	updated, err := s.userRepo.UpdatePasswordHash(ctx, user.ID, user.Password, hashedPassword)
+	if err != nil {
+		logger.Log.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to store rehashed password")
+		return
+	}
+	if updated {
+		user.Password = hashedPassword
+		user.Version++
+	}
+}
+
+// checkThrottle returns a *LoginThrottledError while logins for the account or client are on hold
+func (s *authService) checkThrottle(ctx context.Context, email, clientIP string) error {
+	err := s.loginThrottle.Check(ctx, email, clientIP)
+	if err == nil {
+		return nil
+	}
+
+	var throttled *LoginThrottledError
+	if errors.As(err, &throttled) {
+		return throttled
+	}
+	return fmt.Errorf("failed to login: %w", err)
+}
+
+// loginFailed records a failed attempt and returns the error to report for it
+func (s *authService) loginFailed(ctx context.Context, email, clientIP string, reason error) error {
+	if err := s.loginThrottle.RecordFailure(ctx, email, clientIP); err != nil {
+		return fmt.Errorf("failed to login: %w", err)
+	}
+	return reason
+}
+
+// completeLogin clears the failure counters of the account and client and starts a session
+func (s *authService) completeLogin(ctx context.Context, user *entity.User, client ClientInfo) (*model.TokenResponse, error) {
+	if err := s.loginThrottle.RecordSuccess(ctx, user.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, user, client)
+}
+
+// startSession records a session for the client and issues tokens in a new refresh token family
+func (s *authService) startSession(ctx context.Context, user *entity.User, client ClientInfo) (*model.TokenResponse, error) {
+	familyID, err := token.NewID()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate token family")
+		return nil, err
+	}
+
+	if err := s.createSession(ctx, user, familyID, client); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user, familyID)
+}
+
+// createSession records the client signed in with a refresh token family
+func (s *authService) createSession(ctx context.Context, user *entity.User, familyID string, client ClientInfo) error {
+	if err := s.sessionRepo.Create(ctx, &entity.Session{
+		UserID:     user.ID,
+		FamilyID:   familyID,
+		Device:     useragent.Describe(client.UserAgent),
+		IPAddress:  client.IPAddress,
+		UserAgent:  client.UserAgent,
+		LastSeenAt: time.Now(),
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create session")
+		return err
+	}
+	return nil
+}
+
+// touchSession records activity on the session of a refresh token family, recreating
+// the session for families started before sessions were tracked
+func (s *authService) touchSession(ctx context.Context, user *entity.User, familyID string, client ClientInfo) error {
+	session, err := s.sessionRepo.GetByFamilyID(ctx, familyID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Error().Err(err).Msg("Failed to get session")
+			return err
+		}
+		return s.createSession(ctx, user, familyID, client)
+	}
+
+	if err := s.sessionRepo.Touch(ctx, session.ID, client.IPAddress, time.Now()); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to touch session")
+		return err
+	}
+	return nil
+}
+
+// issueTokens signs a new access token and persists a new refresh token in the given family
+func (s *authService) issueTokens(ctx context.Context, user *entity.User, familyID string) (*model.TokenResponse, error) {
+	accessToken, _, err := s.tokens.Generate(user.ID, user.Role, familyID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to sign token")
+		return nil, err
+	}
+
+	rawRefreshToken, refreshTokenHash, err := token.NewOpaque()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate refresh token")
+		return nil, err
+	}
+
+	if err := s.refreshTokenRepo.Create(ctx, &entity.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: refreshTokenHash,
+		ExpiresAt: time.Now().Add(s.config.RefreshTTL),
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to store refresh token")
+		return nil, err
+	}
+
+	return &model.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: rawRefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.tokens.TTL().Seconds()),
+	}, nil
+}
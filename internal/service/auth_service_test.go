@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/pkg/password"
+	"echto/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// The revocation must outlive every token the user may hold, including the impersonation
+// tokens they issued as an admin
+func TestAuthService_RevokeAllTokens(t *testing.T) {
+	tests := []struct {
+		name             string
+		accessTTL        time.Duration
+		impersonationTTL time.Duration
+		expectedTTL      time.Duration
+	}{
+		{name: "access tokens outlive impersonation", accessTTL: time.Hour, impersonationTTL: 15 * time.Minute, expectedTTL: time.Hour},
+		{name: "impersonation outlives access tokens", accessTTL: 15 * time.Minute, impersonationTTL: time.Hour, expectedTTL: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revocations := new(mockRevocationRepository)
+			refreshTokens := new(mockRefreshTokenRepository)
+			sessions := new(mockSessionRepository)
+			revocations.On("RevokeUser", uint(1), mock.Anything).Return(nil)
+			refreshTokens.On("RevokeByUser", uint(1)).Return(nil)
+			sessions.On("DeleteByUser", uint(1)).Return(nil)
+
+			s := NewAuthService(nil, refreshTokens, revocations, sessions, nil, nil, nil, nil,
+				token.NewManager("test-secret", tt.accessTTL), AuthServiceConfig{ImpersonationTTL: tt.impersonationTTL})
+			before := time.Now()
+			err := s.RevokeAllTokens(context.Background(), 1)
+
+			assert.NoError(t, err)
+			expiresAt := revocations.Calls[0].Arguments.Get(1).(time.Time)
+			assert.WithinDuration(t, before.Add(tt.expectedTTL), expiresAt, time.Second)
+			refreshTokens.AssertExpectations(t)
+			sessions.AssertExpectations(t)
+		})
+	}
+}
+
+// Wrong current passwords count against the account like failed logins, until it is locked
+func TestAuthService_ChangePassword_Throttled(t *testing.T) {
+	hasher, err := password.NewHasher(password.Config{Algorithm: password.AlgorithmBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+	policy, err := password.NewPolicy(password.PolicyConfig{MinLength: 8})
+	require.NoError(t, err)
+	hash, err := hasher.Hash("current-secret")
+	require.NoError(t, err)
+
+	wrong := &model.PasswordChangeRequest{CurrentPassword: "guessed-secret", NewPassword: "new-secret-42"}
+	right := &model.PasswordChangeRequest{CurrentPassword: "current-secret", NewPassword: "new-secret-42"}
+
+	tests := []struct {
+		name             string
+		attempts         []*model.PasswordChangeRequest
+		expectedErr      error
+		expectedLocked   bool
+		expectedFailures int
+	}{
+		{
+			name:             "wrong password is counted",
+			attempts:         []*model.PasswordChangeRequest{wrong},
+			expectedErr:      ErrInvalidCurrentPassword,
+			expectedFailures: 1,
+		},
+		{
+			name:             "locked after repeated failures",
+			attempts:         []*model.PasswordChangeRequest{wrong, wrong, wrong, right},
+			expectedLocked:   true,
+			expectedFailures: 3,
+		},
+		{
+			name:             "right password clears the counter",
+			attempts:         []*model.PasswordChangeRequest{wrong, right},
+			expectedFailures: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newFakeUserRepository(entity.User{ID: 1, Name: "John Doe", Email: "john@example.com", Password: hash, Version: 1})
+			failures := newFakeLoginFailureRepository()
+			throttle := NewLoginThrottleService(failures, LoginThrottleConfig{MaxAccountFailures: 3, LockoutDuration: 15 * time.Minute})
+			revocations := new(mockRevocationRepository)
+			refreshTokens := new(mockRefreshTokenRepository)
+			sessions := new(mockSessionRepository)
+			revocations.On("RevokeUser", uint(1), mock.Anything).Return(nil)
+			refreshTokens.On("RevokeByUser", uint(1)).Return(nil)
+			sessions.On("DeleteByUser", uint(1)).Return(nil)
+
+			s := NewAuthService(users, refreshTokens, revocations, sessions, nil, throttle, hasher, policy,
+				token.NewManager("test-secret", time.Hour), AuthServiceConfig{})
+
+			var err error
+			for _, attempt := range tt.attempts {
+				err = s.ChangePassword(context.Background(), 1, attempt)
+			}
+
+			if tt.expectedLocked {
+				var throttled *LoginThrottledError
+				require.ErrorAs(t, err, &throttled)
+				assert.True(t, throttled.Locked)
+				assert.Equal(t, 0, users.writes)
+			} else {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			}
+			assert.Equal(t, tt.expectedFailures, failures.failures["email:john@example.com"].Failures)
+			// Only the account is counted, the request carries no client IP
+			assert.NotContains(t, failures.failures, ipKey(""))
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/internal/repository"
+	"echto/pkg/logger"
+	"echto/pkg/notifier"
+	"echto/pkg/token"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EmailVerificationService interface {
+	SendVerification(ctx context.Context, user *entity.User)
+	ResendVerification(ctx context.Context, req *model.EmailVerificationResendRequest) error
+	ConfirmEmail(ctx context.Context, req *model.EmailVerificationConfirmRequest) error
+}
+
+type emailVerificationService struct {
+	userRepo              repository.UserRepository
+	verificationTokenRepo repository.EmailVerificationTokenRepository
+	notifier              notifier.Notifier
+	ttl                   time.Duration
+	verificationURL       string
+}
+
+func NewEmailVerificationService(userRepo repository.UserRepository, verificationTokenRepo repository.EmailVerificationTokenRepository, notifier notifier.Notifier, ttl time.Duration, verificationURL string) EmailVerificationService {
+	return &emailVerificationService{
+		userRepo:              userRepo,
+		verificationTokenRepo: verificationTokenRepo,
+		notifier:              notifier,
+		ttl:                   ttl,
+		verificationURL:       verificationURL,
+	}
+}
+
+// SendVerification issues a verification token for the user's current email and
+// delivers it in the background, outliving the request that triggered it
+func (s *emailVerificationService) SendVerification(ctx context.Context, user *entity.User) {
+	go s.issueToken(context.WithoutCancel(ctx), user.ID, user.Email)
+}
+
+// ResendVerification sends a new link to an unverified account. Like password reset
+// requests, it does not reveal whether the email belongs to an account.
+func (s *emailVerificationService) ResendVerification(ctx context.Context, req *model.EmailVerificationResendRequest) error {
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return fmt.Errorf("failed to resend verification: %w", err)
+	}
+
+	if user.EmailVerifiedAt == nil {
+		s.SendVerification(ctx, user)
+	}
+
+	return nil
+}
+
+func (s *emailVerificationService) ConfirmEmail(ctx context.Context, req *model.EmailVerificationConfirmRequest) error {
+	// Look up the presented token by its hash
+	verificationToken, err := s.verificationTokenRepo.GetByHash(ctx, token.Hash(req.Token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get email verification token")
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if verificationToken.UsedAt != nil || time.Now().After(verificationToken.ExpiresAt) {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, verificationToken.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	// The email changed after the token was sent
+	if user.Email != verificationToken.Email {
+		return ErrInvalidVerificationToken
+	}
+
+	used, err := s.verificationTokenRepo.MarkUsed(ctx, verificationToken.ID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to consume email verification token")
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if !used {
+		return ErrInvalidVerificationToken
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to mark email as verified")
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return nil
+}
+
+// issueToken replaces any outstanding tokens of the user and sends a new verification link
+func (s *emailVerificationService) issueToken(ctx context.Context, userID uint, email string) {
+	if err := s.verificationTokenRepo.InvalidateByUser(ctx, userID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to invalidate email verification tokens")
+		return
+	}
+
+	rawToken, tokenHash, err := token.NewOpaque()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate email verification token")
+		return
+	}
+
+	if err := s.verificationTokenRepo.Create(ctx, &entity.EmailVerificationToken{
+		UserID:    userID,
+		Email:     email,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to store email verification token")
+		return
+	}
+
+	if err := s.notifier.Send(notifier.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Use the link below to verify your email address. It expires in %s.\n\n%s",
+			s.ttl, tokenLink(s.verificationURL, rawToken)),
+	}); err != nil {
+		logger.Log.Error().Err(err).Uint("user_id", userID).Msg("Failed to send email verification notification")
+	}
+}
+
+// tokenLink appends the token as a query parameter to a frontend URL
+func tokenLink(baseURL, rawToken string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return rawToken
+	}
+	query := u.Query()
+	query.Set("token", rawToken)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"echto/internal/repository"
+	"errors"
+)
+
+// Domain errors returned by the services. Handlers map them to HTTP responses, so
+// any other error a service returns is reported as an internal failure.
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrEmailExists      = errors.New("email already exists")
+	ErrEmailNotVerified = errors.New("email not verified")
+	ErrInvalidSort      = errors.New("invalid sort")
+	ErrInvalidDateRange = errors.New("invalid date range")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+
+	// ErrVersionMismatch rejects a write conditional on a version the user no longer has,
+	// ErrVersionRequired an unconditional write when conditions are required.
+	// ErrConcurrentUpdate is the repository error for an unconditional write that lost a
+	// race, which services pass on wrapped.
+	ErrVersionMismatch  = errors.New("version mismatch")
+	ErrVersionRequired  = errors.New("version required")
+	ErrConcurrentUpdate = repository.ErrStaleVersion
+
+	ErrInvalidCredentials       = errors.New("invalid credentials")
+	ErrInvalidCurrentPassword   = errors.New("invalid current password")
+	ErrInvalidChallengeToken    = errors.New("invalid challenge token")
+	ErrInvalidRefreshToken      = errors.New("invalid refresh token")
+	ErrRefreshTokenReused       = errors.New("refresh token reused")
+	ErrInvalidResetToken        = errors.New("invalid reset token")
+	ErrInvalidVerificationToken = errors.New("invalid verification token")
+
+	ErrTwoFactorAlreadyEnabled = errors.New("two factor already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two factor not enrolled")
+	// ErrInvalidTwoFactorCode rejects a code at login, ErrInvalidConfirmationCode while enrolling
+	ErrInvalidTwoFactorCode    = errors.New("invalid two factor code")
+	ErrInvalidConfirmationCode = errors.New("invalid confirmation code")
+
+	ErrAPIKeyNotFound  = errors.New("api key not found")
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrCannotImpersonateSelf  = errors.New("cannot impersonate self")
+	ErrCannotImpersonateAdmin = errors.New("cannot impersonate admin")
+
+	ErrOIDCProviderNotFound  = errors.New("oidc provider not found")
+	ErrInvalidOIDCState      = errors.New("invalid oidc state")
+	ErrOIDCLoginFailed       = errors.New("oidc login failed")
+	ErrIdentityEmailConflict = errors.New("identity email conflict")
+)
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"echto/internal/repository"
+	"echto/pkg/logger"
+	"strings"
+	"time"
+)
+
+// LoginThrottledError is returned while an account or client IP has to wait before trying to log in again
+type LoginThrottledError struct {
+	// RetryAfter is how long the caller has to wait before the next attempt
+	RetryAfter time.Duration
+	// Locked reports a lockout after too many failures rather than a backoff delay
+	Locked bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "account locked"
+	}
+	return "too many login attempts"
+}
+
+type LoginThrottleService interface {
+	Check(ctx context.Context, email, clientIP string) error
+	RecordFailure(ctx context.Context, email, clientIP string) error
+	RecordSuccess(ctx context.Context, email, clientIP string) error
+	Reset(ctx context.Context, email string) error
+}
+
+// LoginThrottleConfig holds the settings of LoginThrottleService
+type LoginThrottleConfig struct {
+	// MaxAccountFailures locks an account after that many consecutive failures
+	MaxAccountFailures int
+	// MaxIPFailures locks a client IP after that many consecutive failures across all accounts
+	MaxIPFailures int
+	// LockoutDuration is how long a lockout lasts; it is also the window after which counters start over
+	LockoutDuration time.Duration
+	// BackoffBase is the delay after the first failure, doubled with every further failure
+	BackoffBase time.Duration
+	// BackoffMax caps the backoff delay
+	BackoffMax time.Duration
+}
+
+type loginThrottleService struct {
+	loginFailureRepo repository.LoginFailureRepository
+	config           LoginThrottleConfig
+}
+
+func NewLoginThrottleService(loginFailureRepo repository.LoginFailureRepository, config LoginThrottleConfig) LoginThrottleService {
+	return &loginThrottleService{
+		loginFailureRepo: loginFailureRepo,
+		config:           config,
+	}
+}
+
+// Check returns a *LoginThrottledError while attempts for the account or client IP are on
+// hold. Like the other methods it leaves the IP alone when clientIP is empty, for attempts
+// that are only counted against the account.
+func (s *loginThrottleService) Check(ctx context.Context, email, clientIP string) error {
+	keys := []string{accountKey(email)}
+	if clientIP != "" {
+		keys = append(keys, ipKey(clientIP))
+	}
+	failures, err := s.loginFailureRepo.GetByKeys(ctx, keys...)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get login failures")
+		return err
+	}
+
+	now := time.Now()
+	var throttled *LoginThrottledError
+	for _, failure := range failures {
+		var until time.Time
+		locked := false
+		if failure.LockedUntil != nil && failure.LockedUntil.After(now) {
+			until = *failure.LockedUntil
+			locked = true
+		} else {
+			until = failure.LastFailedAt.Add(s.backoff(failure.Failures))
+		}
+
+		if !until.After(now) {
+			continue
+		}
+		if throttled == nil || until.Sub(now) > throttled.RetryAfter {
+			throttled = &LoginThrottledError{RetryAfter: until.Sub(now), Locked: locked}
+		}
+	}
+
+	if throttled != nil {
+		return throttled
+	}
+	return nil
+}
+
+func (s *loginThrottleService) RecordFailure(ctx context.Context, email, clientIP string) error {
+	if err := s.recordFailure(ctx, accountKey(email), s.config.MaxAccountFailures); err != nil {
+		return err
+	}
+	if clientIP == "" {
+		return nil
+	}
+	return s.recordFailure(ctx, ipKey(clientIP), s.config.MaxIPFailures)
+}
+
+// RecordSuccess clears the counter of the account and takes one failure off the counter
+// of the client IP. The IP counter only decays, so a single valid account cannot be used
+// to wipe the failures of an IP guessing at other accounts.
+func (s *loginThrottleService) RecordSuccess(ctx context.Context, email, clientIP string) error {
+	if err := s.Reset(ctx, email); err != nil {
+		return err
+	}
+	if clientIP == "" {
+		return nil
+	}
+	if err := s.loginFailureRepo.Decrement(ctx, ipKey(clientIP)); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to decrement login failures")
+		return err
+	}
+	return nil
+}
+
+func (s *loginThrottleService) Reset(ctx context.Context, email string) error {
+	if err := s.loginFailureRepo.Delete(ctx, accountKey(email)); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to reset login failures")
+		return err
+	}
+	return nil
+}
+
+// recordFailure bumps the counter of a key and locks it once max is reached
+func (s *loginThrottleService) recordFailure(ctx context.Context, key string, max int) error {
+	failure, err := s.loginFailureRepo.Increment(ctx, key, s.config.LockoutDuration)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to record login failure")
+		return err
+	}
+
+	if max <= 0 || failure.Failures < max {
+		return nil
+	}
+
+	logger.Log.Warn().
+		Str("key", key).
+		Int("failures", failure.Failures).
+		Msg("Too many failed logins, locking")
+
+	if err := s.loginFailureRepo.Lock(ctx, key, time.Now().Add(s.config.LockoutDuration)); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to lock login")
+		return err
+	}
+
+	return nil
+}
+
+// maxBackoffDoublings bounds how often the backoff delay is doubled, which keeps it from
+// overflowing when BackoffMax does not cap it
+const maxBackoffDoublings = 20
+
+// backoff returns the delay required after the given number of consecutive failures
+func (s *loginThrottleService) backoff(failures int) time.Duration {
+	if failures <= 0 || s.config.BackoffBase <= 0 {
+		return 0
+	}
+
+	delay := s.config.BackoffBase
+	for i := 1; i < failures && i <= maxBackoffDoublings && (s.config.BackoffMax <= 0 || delay < s.config.BackoffMax); i++ {
+		delay *= 2
+	}
+	if s.config.BackoffMax > 0 && delay > s.config.BackoffMax {
+		return s.config.BackoffMax
+	}
+	return delay
+}
+
+func accountKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(clientIP string) string {
+	return "ip:" + clientIP
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginThrottleService_Backoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		backoffMax time.Duration
+		failures   int
+		expected   time.Duration
+	}{
+		{name: "no failures", backoffMax: time.Minute, failures: 0, expected: 0},
+		{name: "first failure", backoffMax: time.Minute, failures: 1, expected: time.Second},
+		{name: "doubled per failure", backoffMax: time.Minute, failures: 4, expected: 8 * time.Second},
+		{name: "capped by the maximum", backoffMax: time.Minute, failures: 1000, expected: time.Minute},
+		{name: "uncapped does not overflow", backoffMax: 0, failures: 1000, expected: time.Second << maxBackoffDoublings},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &loginThrottleService{config: LoginThrottleConfig{BackoffBase: time.Second, BackoffMax: tt.backoffMax}}
+
+			assert.Equal(t, tt.expected, s.backoff(tt.failures))
+		})
+	}
+}
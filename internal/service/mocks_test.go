@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"echto/internal/entity"
+	"echto/internal/repository"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// fakeUserRepository keeps users in memory and versions them the way userRepository
+// does. Methods the tests do not need panic through the embedded nil interface.
+type fakeUserRepository struct {
+	repository.UserRepository
+	users map[uint]entity.User
+	// writes counts the updates that were stored
+	writes int
+}
+
+func newFakeUserRepository(users ...entity.User) *fakeUserRepository {
+	r := &fakeUserRepository{users: make(map[uint]entity.User)}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *fakeUserRepository) GetByID(ctx context.Context, id uint) (*entity.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+func (r *fakeUserRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepository) Update(ctx context.Context, user *entity.User) error {
+	stored, ok := r.users[user.ID]
+	if !ok || stored.Version != user.Version {
+		return repository.ErrStaleVersion
+	}
+	user.Version++
+	r.users[user.ID] = *user
+	r.writes++
+	return nil
+}
+
+func (r *fakeUserRepository) UpdateTOTPStep(ctx context.Context, id uint, step int64) (bool, error) {
+	stored, ok := r.users[id]
+	if !ok || stored.TOTPLastStep >= step {
+		return false, nil
+	}
+	stored.TOTPLastStep = step
+	stored.Version++
+	r.users[id] = stored
+	r.writes++
+	return true, nil
+}
+
+func (r *fakeUserRepository) UpdatePasswordHash(ctx context.Context, id uint, oldHash, newHash string) (bool, error) {
+	stored, ok := r.users[id]
+	if !ok || stored.Password != oldHash {
+		return false, nil
+	}
+	stored.Password = newHash
+	stored.Version++
+	r.users[id] = stored
+	r.writes++
+	return true, nil
+}
+
+// fakeTransactor runs the function without a database; the fakes do not roll back
+type fakeTransactor struct{}
+
+func (fakeTransactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type mockAuthService struct {
+	AuthService
+	mock.Mock
+}
+
+func (m *mockAuthService) RevokeAllTokens(ctx context.Context, userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+type mockPasswordResetTokenRepository struct {
+	repository.PasswordResetTokenRepository
+	mock.Mock
+}
+
+func (m *mockPasswordResetTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error) {
+	args := m.Called(tokenHash)
+	return args.Get(0).(*entity.PasswordResetToken), args.Error(1)
+}
+
+func (m *mockPasswordResetTokenRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockPasswordResetTokenRepository) InvalidateByUser(ctx context.Context, userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+type mockLinkedIdentityRepository struct {
+	repository.LinkedIdentityRepository
+	mock.Mock
+}
+
+func (m *mockLinkedIdentityRepository) Create(ctx context.Context, identity *entity.LinkedIdentity) error {
+	args := m.Called(identity.UserID, identity.Provider, identity.Subject)
+	return args.Error(0)
+}
+
+func (m *mockLinkedIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*entity.LinkedIdentity, error) {
+	args := m.Called(provider, subject)
+	return args.Get(0).(*entity.LinkedIdentity), args.Error(1)
+}
+
+type mockRevocationRepository struct {
+	repository.RevocationRepository
+	mock.Mock
+}
+
+func (m *mockRevocationRepository) RevokeUser(ctx context.Context, userID uint, expiresAt time.Time) error {
+	args := m.Called(userID, expiresAt)
+	return args.Error(0)
+}
+
+type mockRefreshTokenRepository struct {
+	repository.RefreshTokenRepository
+	mock.Mock
+}
+
+func (m *mockRefreshTokenRepository) RevokeByUser(ctx context.Context, userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+type mockSessionRepository struct {
+	repository.SessionRepository
+	mock.Mock
+}
+
+func (m *mockSessionRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+// fakeLoginFailureRepository keeps login failure counters in memory
+type fakeLoginFailureRepository struct {
+	repository.LoginFailureRepository
+	failures map[string]entity.LoginFailure
+}
+
+func newFakeLoginFailureRepository() *fakeLoginFailureRepository {
+	return &fakeLoginFailureRepository{failures: make(map[string]entity.LoginFailure)}
+}
+
+func (r *fakeLoginFailureRepository) GetByKeys(ctx context.Context, keys ...string) ([]entity.LoginFailure, error) {
+	var failures []entity.LoginFailure
+	for _, key := range keys {
+		if failure, ok := r.failures[key]; ok {
+			failures = append(failures, failure)
+		}
+	}
+	return failures, nil
+}
+
+func (r *fakeLoginFailureRepository) Increment(ctx context.Context, key string, window time.Duration) (*entity.LoginFailure, error) {
+	failure := r.failures[key]
+	failure.Key = key
+	failure.Failures++
+	failure.LastFailedAt = time.Now()
+	r.failures[key] = failure
+	return &failure, nil
+}
+
+func (r *fakeLoginFailureRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	failure := r.failures[key]
+	failure.LockedUntil = &until
+	r.failures[key] = failure
+	return nil
+}
+
+func (r *fakeLoginFailureRepository) Delete(ctx context.Context, key string) error {
+	delete(r.failures, key)
+	return nil
+}
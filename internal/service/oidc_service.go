@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/internal/repository"
+	"echto/pkg/encryption"
+	"echto/pkg/logger"
+	"echto/pkg/oidc"
+	"echto/pkg/password"
+	"echto/pkg/token"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// oidcStateTTL bounds how long a user may take to log in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+type OIDCService interface {
+	StartLogin(ctx context.Context, provider string) (*model.OIDCLoginStart, error)
+	CompleteLogin(ctx context.Context, provider string, req *model.OIDCCallbackRequest, sealedState string, client ClientInfo) (*model.LoginResponse, error)
+}
+
+// oidcLoginState is kept sealed on the user agent between the redirect to the provider and the callback
+type oidcLoginState struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
+type oidcService struct {
+	userRepo                 repository.UserRepository
+	linkedIdentityRepo       repository.LinkedIdentityRepository
+	authService              AuthService
+	emailVerificationService EmailVerificationService
+	hasher                   *password.Hasher
+	cipher                   *encryption.Cipher
+	providers                map[string]oidc.Provider
+}
+
+func NewOIDCService(userRepo repository.UserRepository, linkedIdentityRepo repository.LinkedIdentityRepository, authService AuthService, emailVerificationService EmailVerificationService, hasher *password.Hasher, cipher *encryption.Cipher, providers map[string]oidc.Provider) OIDCService {
+	return &oidcService{
+		userRepo:                 userRepo,
+		linkedIdentityRepo:       linkedIdentityRepo,
+		authService:              authService,
+		emailVerificationService: emailVerificationService,
+		hasher:                   hasher,
+		cipher:                   cipher,
+		providers:                providers,
+	}
+}
+
+func (s *oidcService) StartLogin(ctx context.Context, provider string) (*model.OIDCLoginStart, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	state, err := token.NewID()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate OIDC state")
+		return nil, fmt.Errorf("failed to start oidc login: %w", err)
+	}
+	nonce, err := token.NewID()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate OIDC nonce")
+		return nil, fmt.Errorf("failed to start oidc login: %w", err)
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate PKCE verifier")
+		return nil, fmt.Errorf("failed to start oidc login: %w", err)
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("provider", provider).Msg("Failed to build OIDC authorization URL")
+		return nil, fmt.Errorf("failed to start oidc login: %w", err)
+	}
+
+	sealed, err := s.sealState(&oidcLoginState{
+		Provider:     provider,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL).Unix(),
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to seal OIDC state")
+		return nil, fmt.Errorf("failed to start oidc login: %w", err)
+	}
+
+	return &model.OIDCLoginStart{AuthURL: authURL, State: sealed}, nil
+}
+
+func (s *oidcService) CompleteLogin(ctx context.Context, provider string, req *model.OIDCCallbackRequest, sealedState string, client ClientInfo) (*model.LoginResponse, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	// The state must match the one issued to this user agent for this provider
+	loginState, err := s.openState(sealedState)
+	if err != nil ||
+		loginState.Provider != provider ||
+		time.Now().Unix() > loginState.ExpiresAt ||
+		subtle.ConstantTimeCompare([]byte(loginState.State), []byte(req.State)) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+
+	// The provider reports a denied or failed login
+	if req.Error != "" || req.Code == "" {
+		logger.Log.Warn().Str("provider", provider).Str("error", req.Error).Str("description", req.ErrorDescription).Msg("OIDC provider returned an error")
+		return nil, ErrOIDCLoginFailed
+	}
+
+	identity, err := p.Exchange(ctx, req.Code, loginState.CodeVerifier)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("provider", provider).Msg("Failed to exchange OIDC authorization code")
+		return nil, ErrOIDCLoginFailed
+	}
+	if subtle.ConstantTimeCompare([]byte(identity.Nonce), []byte(loginState.Nonce)) != 1 {
+		logger.Log.Warn().Str("provider", provider).Msg("OIDC nonce mismatch")
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.LoginUser(ctx, user, client)
+}
+
+// resolveUser returns the user linked to the identity, linking an existing user with
+// the same verified email or provisioning a new user on first login
+func (s *oidcService) resolveUser(ctx context.Context, provider string, identity *oidc.Identity) (*entity.User, error) {
+	linked, err := s.linkedIdentityRepo.GetByProviderSubject(ctx, provider, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(ctx, linked.UserID)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to get linked user")
+			return nil, fmt.Errorf("failed to login: %w", err)
+		}
+		if err := s.linkedIdentityRepo.TouchLastLogin(ctx, linked.ID, time.Now()); err != nil {
+			logger.Log.Warn().Err(err).Msg("Failed to record linked identity login")
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Log.Error().Err(err).Msg("Failed to get linked identity")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	if identity.Email == "" {
+		logger.Log.Warn().Str("provider", provider).Msg("OIDC identity has no email")
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		// Only an address both the provider and the account vouch for may be linked. An
+		// unverified account may have been registered by someone else ahead of the owner,
+		// whose password would keep working after the link.
+		if !identity.EmailVerified || user.EmailVerifiedAt == nil {
+			return nil, ErrIdentityEmailConflict
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if user, err = s.provisionUser(ctx, identity); err != nil {
+			return nil, err
+		}
+	default:
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	now := time.Now()
+	if err := s.linkedIdentityRepo.Create(ctx, &entity.LinkedIdentity{
+		UserID:      user.ID,
+		Provider:    provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to link identity")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	return user, nil
+}
+
+// provisionUser creates a user for a first-time OIDC login. The account gets a random
+// password nobody knows; a password can be set later through a password reset.
+func (s *oidcService) provisionUser(ctx context.Context, identity *oidc.Identity) (*entity.User, error) {
+	randomPassword, _, err := token.NewOpaque()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate password")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+	hashedPassword, err := s.hasher.Hash(randomPassword)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to hash password")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	user := &entity.User{
+		Name:     name,
+		Email:    identity.Email,
+		Password: hashedPassword,
+		Role:     entity.RoleUser,
+	}
+	if identity.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create user")
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	if user.EmailVerifiedAt == nil {
+		s.emailVerificationService.SendVerification(ctx, user)
+	}
+
+	return user, nil
+}
+
+func (s *oidcService) sealState(state *oidcLoginState) (string, error) {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	return s.cipher.Encrypt(string(payload))
+}
+
+func (s *oidcService) openState(sealed string) (*oidcLoginState, error) {
+	payload, err := s.cipher.Decrypt(sealed)
+	if err != nil {
+		return nil, err
+	}
+	var state oidcLoginState
+	if err := json.Unmarshal([]byte(payload), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
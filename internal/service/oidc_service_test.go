@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"echto/internal/entity"
+	"echto/pkg/oidc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestOIDCService_ResolveUser_ExistingAccount(t *testing.T) {
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name                string
+		emailVerifiedAt     *time.Time
+		identityVerified    bool
+		expectedErr         error
+		expectedLinkedOwner uint
+	}{
+		{name: "both verified", emailVerifiedAt: &verifiedAt, identityVerified: true, expectedLinkedOwner: 1},
+		{name: "provider has not verified the email", emailVerifiedAt: &verifiedAt, identityVerified: false, expectedErr: ErrIdentityEmailConflict},
+		{name: "local account has not verified the email", emailVerifiedAt: nil, identityVerified: true, expectedErr: ErrIdentityEmailConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newFakeUserRepository(entity.User{ID: 1, Email: "john@example.com", EmailVerifiedAt: tt.emailVerifiedAt})
+			identities := new(mockLinkedIdentityRepository)
+			identities.On("GetByProviderSubject", "sso", "subject-1").Return((*entity.LinkedIdentity)(nil), gorm.ErrRecordNotFound)
+			if tt.expectedErr == nil {
+				identities.On("Create", tt.expectedLinkedOwner, "sso", "subject-1").Return(nil)
+			}
+
+			s := &oidcService{userRepo: users, linkedIdentityRepo: identities}
+			user, err := s.resolveUser(context.Background(), "sso", &oidc.Identity{
+				Subject:       "subject-1",
+				Email:         "john@example.com",
+				EmailVerified: tt.identityVerified,
+			})
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, user)
+				identities.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedLinkedOwner, user.ID)
+			}
+			identities.AssertExpectations(t)
+		})
+	}
+}
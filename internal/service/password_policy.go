@@ -0,0 +1,24 @@
+package service
+
+import "echto/pkg/password"
+
+// PasswordPolicyError is returned when a new password does not satisfy the password policy
+type PasswordPolicyError struct {
+	// Field is the request field holding the rejected password
+	Field      string
+	Violations []password.Violation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet policy"
+}
+
+// checkPasswordPolicy returns a *PasswordPolicyError when the password violates the policy.
+// userInfo holds the name and email of the account the password is for.
+func checkPasswordPolicy(policy *password.Policy, field, plain string, userInfo ...string) error {
+	violations := policy.Check(plain, userInfo...)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &PasswordPolicyError{Field: field, Violations: violations}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/internal/repository"
+	"echto/pkg/logger"
+	"echto/pkg/notifier"
+	"echto/pkg/password"
+	"echto/pkg/token"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetService interface {
+	RequestReset(ctx context.Context, req *model.PasswordResetRequest) error
+	ConfirmReset(ctx context.Context, req *model.PasswordResetConfirmRequest) error
+}
+
+type passwordResetService struct {
+	userRepo       repository.UserRepository
+	resetTokenRepo repository.PasswordResetTokenRepository
+	transactor     repository.Transactor
+	authService    AuthService
+	notifier       notifier.Notifier
+	hasher         *password.Hasher
+	passwordPolicy *password.Policy
+	ttl            time.Duration
+	resetURL       string
+}
+
+func NewPasswordResetService(userRepo repository.UserRepository, resetTokenRepo repository.PasswordResetTokenRepository, transactor repository.Transactor, authService AuthService, notifier notifier.Notifier, hasher *password.Hasher, passwordPolicy *password.Policy, ttl time.Duration, resetURL string) PasswordResetService {
+	return &passwordResetService{
+		userRepo:       userRepo,
+		resetTokenRepo: resetTokenRepo,
+		transactor:     transactor,
+		authService:    authService,
+		notifier:       notifier,
+		hasher:         hasher,
+		passwordPolicy: passwordPolicy,
+		ttl:            ttl,
+		resetURL:       resetURL,
+	}
+}
+
+// RequestReset issues a reset token when the email belongs to a user. Unknown emails
+// are not reported, and issuing happens in the background so the response time does
+// not reveal whether the account exists either.
+func (s *passwordResetService) RequestReset(ctx context.Context, req *model.PasswordResetRequest) error {
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return fmt.Errorf("failed to request password reset: %w", err)
+	}
+
+	// The token is issued after the response is sent, so it must not be canceled with the request
+	go s.issueToken(context.WithoutCancel(ctx), user)
+
+	return nil
+}
+
+func (s *passwordResetService) ConfirmReset(ctx context.Context, req *model.PasswordResetConfirmRequest) error {
+	// Look up the presented token by its hash
+	resetToken, err := s.resetTokenRepo.GetByHash(ctx, token.Hash(req.Token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get password reset token")
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, resetToken.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	// Check password strength before the token is consumed so the user can try again
+	if err := checkPasswordPolicy(s.passwordPolicy, "new_password", req.NewPassword, user.Name, user.Email); err != nil {
+		return err
+	}
+
+	// Hash new password
+	hashedPassword, err := s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to hash password")
+		return fmt.Errorf("failed to process password: %w", err)
+	}
+
+	// Consume the token and change the password together. The token is consumed first with
+	// a conditional update, so of two requests racing with the same token the second one
+	// waits for the first and then finds it used.
+	err = s.transactor.Transaction(ctx, func(ctx context.Context) error {
+		used, err := s.resetTokenRepo.MarkUsed(ctx, resetToken.ID)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to consume password reset token")
+			return fmt.Errorf("failed to reset password: %w", err)
+		}
+		if !used {
+			return ErrInvalidResetToken
+		}
+
+		user.Password = hashedPassword
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			if errors.Is(err, repository.ErrStaleVersion) {
+				return ErrConcurrentUpdate
+			}
+			logger.Log.Error().Err(err).Msg("Failed to update password")
+			return fmt.Errorf("failed to reset password: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Other outstanding reset links and existing sessions are no longer valid. Success is
+	// only reported once they are, as the user relies on it to cut off anyone else.
+	if err := s.resetTokenRepo.InvalidateByUser(ctx, user.ID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to invalidate password reset tokens")
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	if err := s.authService.RevokeAllTokens(ctx, user.ID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to revoke sessions after password reset")
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	return nil
+}
+
+// issueToken stores a new reset token for the user and sends the reset link
+func (s *passwordResetService) issueToken(ctx context.Context, user *entity.User) {
+	rawToken, tokenHash, err := token.NewOpaque()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate password reset token")
+		return
+	}
+
+	if err := s.resetTokenRepo.Create(ctx, &entity.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to store password reset token")
+		return
+	}
+
+	if err := s.notifier.Send(notifier.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use the link below to set a new password. It expires in %s.\n\n%s",
+			s.ttl, tokenLink(s.resetURL, rawToken)),
+	}); err != nil {
+		logger.Log.Error().Err(err).Uint("user_id", user.ID).Msg("Failed to send password reset notification")
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/pkg/password"
+	"echto/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordResetService_ConfirmReset(t *testing.T) {
+	hasher, err := password.NewHasher(password.Config{Algorithm: password.AlgorithmBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+	policy, err := password.NewPolicy(password.PolicyConfig{MinLength: 8})
+	require.NoError(t, err)
+
+	req := &model.PasswordResetConfirmRequest{Token: "reset-token", NewPassword: "new-secret-42"}
+	resetToken := &entity.PasswordResetToken{ID: 7, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name            string
+		used            bool
+		mockSetup       func(*mockPasswordResetTokenRepository, *mockAuthService)
+		expectedErr     error
+		passwordChanged bool
+	}{
+		{
+			name: "token consumed",
+			used: true,
+			mockSetup: func(tokens *mockPasswordResetTokenRepository, auth *mockAuthService) {
+				tokens.On("InvalidateByUser", uint(1)).Return(nil)
+				auth.On("RevokeAllTokens", uint(1)).Return(nil)
+			},
+			passwordChanged: true,
+		},
+		{
+			name:        "token already used by a concurrent request",
+			used:        false,
+			mockSetup:   func(tokens *mockPasswordResetTokenRepository, auth *mockAuthService) {},
+			expectedErr: ErrInvalidResetToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newFakeUserRepository(entity.User{ID: 1, Name: "John Doe", Email: "john@example.com", Password: "old-hash", Version: 1})
+			tokens := new(mockPasswordResetTokenRepository)
+			auth := new(mockAuthService)
+			tokens.On("GetByHash", token.Hash(req.Token)).Return(resetToken, nil)
+			tokens.On("MarkUsed", resetToken.ID).Return(tt.used, nil)
+			tt.mockSetup(tokens, auth)
+
+			s := NewPasswordResetService(users, tokens, fakeTransactor{}, auth, nil, hasher, policy, time.Hour, "")
+			err := s.ConfirmReset(context.Background(), req)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			stored, _ := users.GetByID(context.Background(), 1)
+			if tt.passwordChanged {
+				assert.Equal(t, 1, users.writes)
+				ok, err := hasher.Verify(req.NewPassword, stored.Password)
+				assert.NoError(t, err)
+				assert.True(t, ok)
+			} else {
+				assert.Equal(t, 0, users.writes)
+				assert.Equal(t, "old-hash", stored.Password)
+				tokens.AssertNotCalled(t, "InvalidateByUser", mock.Anything)
+				auth.AssertNotCalled(t, "RevokeAllTokens", mock.Anything)
+			}
+			tokens.AssertExpectations(t)
+			auth.AssertExpectations(t)
+		})
+	}
+}
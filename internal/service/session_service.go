@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"echto/internal/model"
+	"echto/internal/repository"
+	"echto/pkg/logger"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sessionTouchInterval limits how often the last-seen timestamp of a session is written
+const sessionTouchInterval = time.Minute
+
+// ClientInfo describes the device a login or refresh request comes from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+type SessionService interface {
+	GetSessions(ctx context.Context, userID uint, currentSessionID string) (*model.SessionListResponse, error)
+	RevokeSession(ctx context.Context, userID, sessionID uint) error
+	TouchSession(ctx context.Context, sessionID string, userID uint, ipAddress string) (bool, error)
+}
+
+type sessionService struct {
+	sessionRepo      repository.SessionRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository, refreshTokenRepo repository.RefreshTokenRepository) SessionService {
+	return &sessionService{
+		sessionRepo:      sessionRepo,
+		refreshTokenRepo: refreshTokenRepo,
+	}
+}
+
+func (s *sessionService) GetSessions(ctx context.Context, userID uint, currentSessionID string) (*model.SessionListResponse, error) {
+	sessions, err := s.sessionRepo.GetByUser(ctx, userID)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get sessions")
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	response := &model.SessionListResponse{Sessions: make([]model.SessionResponse, 0, len(sessions))}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, model.SessionResponse{
+			ID:         session.ID,
+			Device:     session.Device,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    currentSessionID != "" && session.FamilyID == currentSessionID,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *sessionService) RevokeSession(ctx context.Context, userID, sessionID uint) error {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get session")
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	// Sessions of other users are reported as missing
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	// Access tokens of the session are refused once it is gone, see TouchSession
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, session.FamilyID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to revoke refresh token family")
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if err := s.sessionRepo.Delete(ctx, session.ID); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to delete session")
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// TouchSession reports whether the session is still active and records the activity
+func (s *sessionService) TouchSession(ctx context.Context, sessionID string, userID uint, ipAddress string) (bool, error) {
+	session, err := s.sessionRepo.GetByFamilyID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if session.UserID != userID {
+		return false, nil
+	}
+
+	// Recording activity is best effort and throttled to keep writes off the hot path
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval || session.IPAddress != ipAddress {
+		if err := s.sessionRepo.Touch(ctx, session.ID, ipAddress, now); err != nil {
+			logger.Log.Warn().Err(err).Uint("session_id", session.ID).Msg("Failed to record session activity")
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/internal/repository"
+	"echto/pkg/encryption"
+	"echto/pkg/logger"
+	"echto/pkg/token"
+	"echto/pkg/totp"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+type TwoFactorService interface {
+	Enroll(ctx context.Context, userID uint) (*model.TwoFactorEnrollResponse, error)
+	Confirm(ctx context.Context, userID uint, req *model.TwoFactorConfirmRequest) (*model.RecoveryCodesResponse, error)
+	VerifyCode(ctx context.Context, user *entity.User, code string) (bool, error)
+}
+
+type twoFactorService struct {
+	userRepo         repository.UserRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+	cipher           *encryption.Cipher
+	issuer           string
+}
+
+func NewTwoFactorService(userRepo repository.UserRepository, recoveryCodeRepo repository.RecoveryCodeRepository, cipher *encryption.Cipher, issuer string) TwoFactorService {
+	return &twoFactorService{
+		userRepo:         userRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		cipher:           cipher,
+		issuer:           issuer,
+	}
+}
+
+// Enroll generates a new TOTP secret for the user. It only takes effect once confirmed.
+func (s *twoFactorService) Enroll(ctx context.Context, userID uint) (*model.TwoFactorEnrollResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to generate TOTP secret")
+		return nil, fmt.Errorf("failed to enroll two factor: %w", err)
+	}
+
+	encrypted, err := s.cipher.Encrypt(secret)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to encrypt TOTP secret")
+		return nil, fmt.Errorf("failed to enroll two factor: %w", err)
+	}
+
+	user.TOTPSecret = &encrypted
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to store TOTP secret")
+		return nil, fmt.Errorf("failed to enroll two factor: %w", err)
+	}
+
+	return &model.TwoFactorEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(secret, s.issuer, user.Email),
+	}, nil
+}
+
+// Confirm enables 2FA once the user proves their authenticator produces valid codes
+func (s *twoFactorService) Confirm(ctx context.Context, userID uint, req *model.TwoFactorConfirmRequest) (*model.RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	secret, err := s.cipher.Decrypt(*user.TOTPSecret)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to decrypt TOTP secret")
+		return nil, fmt.Errorf("failed to confirm two factor: %w", err)
+	}
+
+	step, ok := totp.Validate(secret, req.Code, time.Now(), 1)
+	if !ok {
+		return nil, ErrInvalidConfirmationCode
+	}
+
+	// Generate recovery codes
+	codes := make([]string, recoveryCodeCount)
+	records := make([]entity.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to generate recovery code")
+			return nil, fmt.Errorf("failed to confirm two factor: %w", err)
+		}
+		codes[i] = code
+		records[i] = entity.RecoveryCode{
+			UserID:   user.ID,
+			CodeHash: token.Hash(normalizeRecoveryCode(code)),
+		}
+	}
+
+	if err := s.recoveryCodeRepo.Replace(ctx, user.ID, records); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to store recovery codes")
+		return nil, fmt.Errorf("failed to confirm two factor: %w", err)
+	}
+
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	user.TOTPLastStep = step
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to enable two factor")
+		return nil, fmt.Errorf("failed to confirm two factor: %w", err)
+	}
+
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyCode checks a TOTP code or an unused recovery code. Both are single-use.
+func (s *twoFactorService) VerifyCode(ctx context.Context, user *entity.User, code string) (bool, error) {
+	if user.TOTPEnabledAt == nil || user.TOTPSecret == nil {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		secret, err := s.cipher.Decrypt(*user.TOTPSecret)
+		if err != nil {
+			return false, err
+		}
+
+		step, ok := totp.Validate(secret, code, time.Now(), 1)
+		if !ok {
+			return false, nil
+		}
+
+		// Reject a code that was already accepted
+		return s.userRepo.UpdateTOTPStep(ctx, user.ID, step)
+	}
+
+	return s.recoveryCodeRepo.Consume(ctx, user.ID, token.Hash(normalizeRecoveryCode(code)))
+}
+
+// generateRecoveryCode returns a code formatted as XXXXX-XXXXX
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = recoveryCodeAlphabet[int(b[i])%len(recoveryCodeAlphabet)]
+	}
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+// normalizeRecoveryCode makes codes comparable regardless of case and separators
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -1,179 +1,525 @@
 package service
 
 import (
+	"context"
 	"echto/internal/entity"
 	"echto/internal/model"
 	"echto/internal/repository"
+	"echto/pkg/cursor"
+	"echto/pkg/highlight"
 	"echto/pkg/logger"
+	"echto/pkg/password"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserService interface {
-	CreateUser(req *model.UserCreateRequest) (*model.UserResponse, error)
-	GetUser(id uint) (*model.UserResponse, error)
-	GetUsers(page, limit int) (*model.UserListResponse, error)
-	UpdateUser(id uint, req *model.UserUpdateRequest) (*model.UserResponse, error)
-	DeleteUser(id uint) error
+	CreateUser(ctx context.Context, req *model.UserCreateRequest) (*model.UserResponse, error)
+	GetUser(ctx context.Context, id uint) (*model.UserResponse, error)
+	GetUsers(ctx context.Context, query *model.UserListQuery) (*model.UserListResponse, error)
+	SearchUsers(ctx context.Context, query *model.UserSearchQuery) (*model.UserSearchResponse, error)
+	// The writes below are conditional on the user still having the given version,
+	// which is 0 for an unconditional write
+	UpdateUser(ctx context.Context, id, version uint, req *model.UserUpdateRequest) (*model.UserResponse, error)
+	// ReplaceUser also takes the version of the user the replacement was derived from, and
+	// fails when the user changed since even if the write is unconditional
+	ReplaceUser(ctx context.Context, id, version, basedOn uint, req *model.UserReplaceRequest) (*model.UserResponse, error)
+	UpdateUserRole(ctx context.Context, id, version uint, req *model.UserRoleUpdateRequest) (*model.UserResponse, error)
+	DeleteUser(ctx context.Context, id, version uint) error
 }
 
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo                 repository.UserRepository
+	emailVerificationService EmailVerificationService
+	hasher                   *password.Hasher
+	passwordPolicy           *password.Policy
+	authService              AuthService
+	// requireVersion refuses unconditional writes
+	requireVersion bool
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, emailVerificationService EmailVerificationService, hasher *password.Hasher, passwordPolicy *password.Policy, authService AuthService, requireVersion bool) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:                 userRepo,
+		emailVerificationService: emailVerificationService,
+		hasher:                   hasher,
+		passwordPolicy:           passwordPolicy,
+		authService:              authService,
+		requireVersion:           requireVersion,
 	}
 }
 
-func (s *userService) CreateUser(req *model.UserCreateRequest) (*model.UserResponse, error) {
+func (s *userService) CreateUser(ctx context.Context, req *model.UserCreateRequest) (*model.UserResponse, error) {
+	// Check password strength
+	if err := checkPasswordPolicy(s.passwordPolicy, "password", req.Password, req.Name, req.Email); err != nil {
+		return nil, err
+	}
+
 	// Check if email already exists
-	existingUser, err := s.userRepo.GetByEmail(req.Email)
+	existingUser, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err == nil && existingUser != nil {
-		return nil, errors.New("email already exists")
+		return nil, ErrEmailExists
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to hash password")
-		return nil, errors.New("failed to process password")
+		return nil, fmt.Errorf("failed to process password: %w", err)
 	}
 
 	// Create user entity
 	user := &entity.User{
 		Name:     req.Name,
 		Email:    req.Email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
+		Role:     entity.RoleUser,
 	}
 
 	// Save to database
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to create user")
-		return nil, errors.New("failed to create user")
+		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// Ask the user to confirm they own the address
+	s.emailVerificationService.SendVerification(ctx, user)
+
 	// Return response
-	return &model.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, nil
+	return toUserResponse(user), nil
 }
 
-func (s *userService) GetUser(id uint) (*model.UserResponse, error) {
-	user, err := s.userRepo.GetByID(id)
+func (s *userService) GetUser(ctx context.Context, id uint) (*model.UserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
-		if err.Error() == "record not found" {
-			return nil, errors.New("user not found")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
 		}
 		logger.Log.Error().Err(err).Msg("Failed to get user")
-		return nil, errors.New("failed to get user")
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	return &model.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, nil
+	return toUserResponse(user), nil
 }
 
-func (s *userService) GetUsers(page, limit int) (*model.UserListResponse, error) {
-	if page < 1 {
-		page = 1
-	}
+func (s *userService) GetUsers(ctx context.Context, query *model.UserListQuery) (*model.UserListResponse, error) {
+	limit := query.Limit
 	if limit < 1 || limit > 100 {
 		limit = 10
 	}
 
-	users, total, err := s.userRepo.GetAll(page, limit)
+	sort, err := parseUserSort(query.Sort)
 	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to get users")
-		return nil, errors.New("failed to get users")
+		return nil, err
+	}
+	if query.CreatedFrom != nil && query.CreatedTo != nil && !query.CreatedTo.After(*query.CreatedFrom) {
+		return nil, ErrInvalidDateRange
+	}
+
+	filter := repository.UserFilter{
+		Query:        query.Query,
+		NameContains: query.Name,
+		EmailDomain:  query.EmailDomain,
+		CreatedFrom:  query.CreatedFrom,
+		CreatedTo:    query.CreatedTo,
+		Sort:         sort,
 	}
 
-	// Convert to response format
-	userResponses := make([]model.UserResponse, len(users))
-	for i, user := range users {
-		userResponses[i] = model.UserResponse{
-			ID:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+	// Cursor pages skip the count unless asked, since it costs a scan of every match
+	byCursor := query.Pagination == "cursor" || query.Cursor != ""
+	includeTotal := !byCursor
+	if query.IncludeTotal != nil {
+		includeTotal = *query.IncludeTotal
+	}
+
+	var response *model.UserListResponse
+	if byCursor {
+		response, err = s.getUsersByCursor(ctx, filter, query.Sort, query.Cursor, limit)
+	} else {
+		response, err = s.getUsersByPage(ctx, filter, query.Page, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if includeTotal {
+		total, err := s.userRepo.Count(ctx, filter)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to count users")
+			return nil, fmt.Errorf("failed to count users: %w", err)
 		}
+		response.Total = &total
+	}
+
+	return response, nil
+}
+
+// getUsersByPage returns a page of users by page number
+func (s *userService) getUsersByPage(ctx context.Context, filter repository.UserFilter, page, limit int) (*model.UserListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	users, err := s.userRepo.GetAll(ctx, filter, page, limit)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get users")
+		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
 
 	return &model.UserListResponse{
-		Users: userResponses,
-		Total: total,
+		Users: toUserResponses(users),
 		Page:  page,
 		Limit: limit,
 	}, nil
 }
 
-func (s *userService) UpdateUser(id uint, req *model.UserUpdateRequest) (*model.UserResponse, error) {
-	// Get existing user
-	user, err := s.userRepo.GetByID(id)
+// getUsersByCursor returns the users next to a cursor, or the first users without one.
+// One extra row is read to tell whether there is a page beyond this one.
+func (s *userService) getUsersByCursor(ctx context.Context, filter repository.UserFilter, sortParam, encoded string, limit int) (*model.UserListResponse, error) {
+	var keyset *repository.Keyset
+	if encoded != "" {
+		var err error
+		if keyset, err = decodeUserCursor(encoded, sortParam, filter.Sort); err != nil {
+			return nil, err
+		}
+	}
+
+	users, err := s.userRepo.GetAllByKeyset(ctx, filter, keyset, limit+1)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get users")
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	backward := keyset != nil && keyset.Backward
+	more := len(users) > limit
+	if more {
+		// Backward pages are read towards the start, so the extra row comes first
+		if backward {
+			users = users[1:]
+		} else {
+			users = users[:limit]
+		}
+	}
+
+	response := &model.UserListResponse{
+		Users: toUserResponses(users),
+		Limit: limit,
+	}
+	if len(users) > 0 {
+		first, last := &users[0], &users[len(users)-1]
+		if more || backward {
+			response.NextCursor = encodeUserCursor(sortParam, filter.Sort, last, false)
+		}
+		if (more && backward) || (keyset != nil && !backward) {
+			response.PrevCursor = encodeUserCursor(sortParam, filter.Sort, first, true)
+		}
+	}
+	return response, nil
+}
+
+func (s *userService) SearchUsers(ctx context.Context, query *model.UserSearchQuery) (*model.UserSearchResponse, error) {
+	limit := query.Limit
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	matches, err := s.userRepo.Search(ctx, query.Query, limit)
 	if err != nil {
-		if err.Error() == "record not found" {
-			return nil, errors.New("user not found")
+		logger.Log.Error().Err(err).Msg("Failed to search users")
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	terms := highlight.Terms(query.Query)
+	results := make([]model.UserSearchResult, len(matches))
+	for i := range matches {
+		user := &matches[i].User
+		results[i] = model.UserSearchResult{
+			User:  *toUserResponse(user),
+			Score: matches[i].Score,
+			Highlights: model.UserSearchHighlights{
+				Name:  highlight.Mark(user.Name, terms),
+				Email: highlight.Mark(user.Email, terms),
+			},
 		}
-		logger.Log.Error().Err(err).Msg("Failed to get user")
-		return nil, errors.New("failed to get user")
+	}
+
+	return &model.UserSearchResponse{Results: results}, nil
+}
+
+func (s *userService) UpdateUser(ctx context.Context, id, version uint, req *model.UserUpdateRequest) (*model.UserResponse, error) {
+	// Get existing user
+	user, err := s.getUserForWrite(ctx, id, version)
+	if err != nil {
+		return nil, err
 	}
 
 	// Update fields if provided
+	name, email := user.Name, user.Email
 	if req.Name != "" {
-		user.Name = req.Name
+		name = req.Name
 	}
 	if req.Email != "" {
+		email = req.Email
+	}
+
+	return s.saveUser(ctx, user, version, name, email)
+}
+
+func (s *userService) ReplaceUser(ctx context.Context, id, version, basedOn uint, req *model.UserReplaceRequest) (*model.UserResponse, error) {
+	// Get existing user
+	user, err := s.getUserForWrite(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// The replacement must not overwrite changes it was not derived from
+	if user.Version != basedOn {
+		return nil, staleVersionError(version)
+	}
+
+	return s.saveUser(ctx, user, version, req.Name, req.Email)
+}
+
+// saveUser stores the editable fields of a user. A changed email address must be
+// unused and is verified again.
+func (s *userService) saveUser(ctx context.Context, user *entity.User, version uint, name, email string) (*model.UserResponse, error) {
+	user.Name = name
+	emailChanged := false
+	if email != user.Email {
 		// Check if email already exists (excluding current user)
-		existingUser, err := s.userRepo.GetByEmail(req.Email)
-		if err == nil && existingUser != nil && existingUser.ID != id {
-			return nil, errors.New("email already exists")
+		existingUser, err := s.userRepo.GetByEmail(ctx, email)
+		if err == nil && existingUser != nil && existingUser.ID != user.ID {
+			return nil, ErrEmailExists
 		}
-		user.Email = req.Email
+		user.Email = email
+		user.EmailVerifiedAt = nil
+		emailChanged = true
 	}
 
 	// Save changes
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrStaleVersion) {
+			return nil, staleVersionError(version)
+		}
 		logger.Log.Error().Err(err).Msg("Failed to update user")
-		return nil, errors.New("failed to update user")
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// A new address has to be verified again
+	if emailChanged {
+		s.emailVerificationService.SendVerification(ctx, user)
 	}
 
 	// Return response
-	return &model.UserResponse{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-	}, nil
+	return toUserResponse(user), nil
 }
 
-func (s *userService) DeleteUser(id uint) error {
-	// Check if user exists
-	_, err := s.userRepo.GetByID(id)
+func (s *userService) UpdateUserRole(ctx context.Context, id, version uint, req *model.UserRoleUpdateRequest) (*model.UserResponse, error) {
+	// Get existing user
+	user, err := s.getUserForWrite(ctx, id, version)
 	if err != nil {
-		if err.Error() == "record not found" {
-			return errors.New("user not found")
+		return nil, err
+	}
+
+	roleChanged := user.Role != req.Role
+	user.Role = req.Role
+
+	// Save changes
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrStaleVersion) {
+			return nil, staleVersionError(version)
 		}
-		logger.Log.Error().Err(err).Msg("Failed to get user")
-		return errors.New("failed to get user")
+		logger.Log.Error().Err(err).Msg("Failed to update user role")
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// Tokens carry the role, so sign out every session issued with the old one
+	if roleChanged {
+		if err := s.authService.RevokeAllTokens(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+	}
+
+	return toUserResponse(user), nil
+}
+
+func (s *userService) DeleteUser(ctx context.Context, id, version uint) error {
+	// Check if user exists
+	user, err := s.getUserForWrite(ctx, id, version)
+	if err != nil {
+		return err
 	}
 
 	// Delete user
-	if err := s.userRepo.Delete(id); err != nil {
+	if err := s.userRepo.Delete(ctx, id, user.Version); err != nil {
+		if errors.Is(err, repository.ErrStaleVersion) {
+			return staleVersionError(version)
+		}
 		logger.Log.Error().Err(err).Msg("Failed to delete user")
-		return errors.New("failed to delete user")
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	// Tokens already issued to the user must not outlive the account
+	if err := s.authService.RevokeAllTokens(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
 	}
 
 	return nil
 }
+
+// getUserForWrite returns the user a write applies to, provided it still has the
+// version the write is conditional on
+func (s *userService) getUserForWrite(ctx context.Context, id, version uint) (*entity.User, error) {
+	if version == 0 && s.requireVersion {
+		return nil, ErrVersionRequired
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		logger.Log.Error().Err(err).Msg("Failed to get user")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if version != 0 && user.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	return user, nil
+}
+
+// staleVersionError returns the error for a write that lost a race with another one
+// after the user was read. Conditional writes fail their precondition.
+func staleVersionError(version uint) error {
+	if version != 0 {
+		return ErrVersionMismatch
+	}
+	return ErrConcurrentUpdate
+}
+
+// userSortColumns whitelists the fields users can be sorted by and their columns
+var userSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"email":      "email",
+	"role":       "role",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// parseUserSort parses a sort parameter such as "role,created_at:desc". Fields are
+// sorted ascending unless followed by :desc, and each may appear only once.
+func parseUserSort(sort string) ([]repository.SortField, error) {
+	if sort == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(sort, ",")
+	fields := make([]repository.SortField, 0, len(parts))
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		name, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+		column, ok := userSortColumns[name]
+		if !ok || seen[column] {
+			return nil, ErrInvalidSort
+		}
+		seen[column] = true
+
+		switch direction {
+		case "", "asc":
+			fields = append(fields, repository.SortField{Column: column})
+		case "desc":
+			fields = append(fields, repository.SortField{Column: column, Desc: true})
+		default:
+			return nil, ErrInvalidSort
+		}
+	}
+	return fields, nil
+}
+
+// encodeUserCursor returns the cursor for the rows after user, or before it when backward
+func encodeUserCursor(sortParam string, sort []repository.SortField, user *entity.User, backward bool) string {
+	values := make([]string, len(sort))
+	for i, field := range sort {
+		values[i] = userSortValue(user, field.Column)
+	}
+	return cursor.Encode(cursor.Cursor{Sort: sortParam, Values: values, ID: user.ID, Backward: backward})
+}
+
+// decodeUserCursor returns the keyset of a cursor, which must have been issued for
+// the same sort parameter
+func decodeUserCursor(encoded, sortParam string, sort []repository.SortField) (*repository.Keyset, error) {
+	c, err := cursor.Decode(encoded)
+	if err != nil || c.Sort != sortParam || len(c.Values) != len(sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(sort))
+	for i, field := range sort {
+		if values[i], err = parseUserSortValue(field.Column, c.Values[i]); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &repository.Keyset{Values: values, ID: c.ID, Backward: c.Backward}, nil
+}
+
+// userSortValue formats the value of a sort column of a user for a cursor
+func userSortValue(user *entity.User, column string) string {
+	switch column {
+	case "id":
+		return strconv.FormatUint(uint64(user.ID), 10)
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "role":
+		return user.Role
+	case "created_at":
+		return user.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		return user.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+// parseUserSortValue parses a cursor value formatted by userSortValue
+func parseUserSortValue(column, value string) (interface{}, error) {
+	switch column {
+	case "id":
+		id, err := strconv.ParseUint(value, 10, 32)
+		return uint(id), err
+	case "created_at", "updated_at":
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return value, nil
+}
+
+// toUserResponses maps user entities to their API representation
+func toUserResponses(users []entity.User) []model.UserResponse {
+	responses := make([]model.UserResponse, len(users))
+	for i := range users {
+		responses[i] = *toUserResponse(&users[i])
+	}
+	return responses
+}
+
+// toUserResponse maps a user entity to its API representation
+func toUserResponse(user *entity.User) *model.UserResponse {
+	return &model.UserResponse{
+		ID:               user.ID,
+		Name:             user.Name,
+		Email:            user.Email,
+		Role:             user.Role,
+		EmailVerifiedAt:  user.EmailVerifiedAt,
+		TwoFactorEnabled: user.TOTPEnabledAt != nil,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+		Version:          user.Version,
+	}
+}
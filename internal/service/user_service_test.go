@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"echto/internal/entity"
+	"echto/internal/model"
+	"echto/pkg/encryption"
+	"echto/pkg/password"
+	"echto/pkg/totp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Writes made while signing in change the user, so a replacement derived from the user
+// before them must fail its version check
+func TestUserService_ReplaceUser_AfterSignInWrite(t *testing.T) {
+	weak, err := password.NewHasher(password.Config{Algorithm: password.AlgorithmBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+	strong, err := password.NewHasher(password.Config{Algorithm: password.AlgorithmBcrypt, BcryptCost: 5})
+	require.NoError(t, err)
+	cipher, err := encryption.NewCipher("test-key")
+	require.NoError(t, err)
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	encryptedSecret, err := cipher.Encrypt(secret)
+	require.NoError(t, err)
+	hash, err := weak.Hash("secret-password")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		write func(t *testing.T, users *fakeUserRepository, user *entity.User)
+	}{
+		{
+			name: "totp verification",
+			write: func(t *testing.T, users *fakeUserRepository, user *entity.User) {
+				code, err := totp.Code(secret, totp.Step(time.Now()))
+				require.NoError(t, err)
+				s := NewTwoFactorService(users, nil, cipher, "echto")
+				ok, err := s.VerifyCode(context.Background(), user, code)
+				require.NoError(t, err)
+				require.True(t, ok)
+			},
+		},
+		{
+			name: "password rehash",
+			write: func(t *testing.T, users *fakeUserRepository, user *entity.User) {
+				s := &authService{userRepo: users, hasher: strong}
+				s.rehashPassword(context.Background(), user, "secret-password")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enabledAt := time.Now()
+			users := newFakeUserRepository(entity.User{
+				ID:            1,
+				Name:          "John Doe",
+				Email:         "john@example.com",
+				Password:      hash,
+				TOTPSecret:    &encryptedSecret,
+				TOTPEnabledAt: &enabledAt,
+				Version:       3,
+			})
+			user, _ := users.GetByID(context.Background(), 1)
+
+			tt.write(t, users, user)
+
+			stored, _ := users.GetByID(context.Background(), 1)
+			assert.Equal(t, uint(4), stored.Version)
+
+			s := NewUserService(users, nil, weak, nil, nil, false)
+			req := &model.UserReplaceRequest{Name: "Jane Doe", Email: "john@example.com"}
+			_, err := s.ReplaceUser(context.Background(), 1, 3, 3, req)
+			assert.ErrorIs(t, err, ErrVersionMismatch)
+			_, err = s.ReplaceUser(context.Background(), 1, 0, 3, req)
+			assert.ErrorIs(t, err, ErrConcurrentUpdate)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrMalformed is returned for a cursor that was not produced by Encode
+var ErrMalformed = errors.New("malformed cursor")
+
+// Cursor marks a row of a keyset-paginated list: the values of the columns the list
+// is sorted by and the ID breaking their ties. Sort records the sort order the cursor
+// was issued for, and Backward asks for the rows before the marked one.
+type Cursor struct {
+	Sort     string   `json:"s,omitempty"`
+	Values   []string `json:"v,omitempty"`
+	ID       uint     `json:"id"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+// Encode returns the opaque, URL-safe form of a cursor
+func Encode(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor returned by Encode
+func Decode(s string) (Cursor, error) {
+	var c Cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrMalformed
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 {
+		return c, ErrMalformed
+	}
+	return c, nil
+}
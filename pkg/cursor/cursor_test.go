@@ -0,0 +1,42 @@
+package cursor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	c := Cursor{
+		Sort:     "role,created_at:desc",
+		Values:   []string{"admin", "2024-01-02T03:04:05.123456Z"},
+		ID:       42,
+		Backward: true,
+	}
+
+	encoded := Encode(c)
+	assert.NotContains(t, encoded, "admin")
+
+	decoded, err := Decode(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, c, decoded)
+}
+
+func TestDecode_Malformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "not json", cursor: "bm90IGpzb24"},
+		{name: "missing id", cursor: Encode(Cursor{Values: []string{"admin"}})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.cursor)
+			assert.ErrorIs(t, err, ErrMalformed)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package highlight
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mark HTML-escapes text and wraps every case-insensitive occurrence of a term in
+// <mark> tags, so the result can be shown as HTML. Longer terms win over terms they
+// contain, and terms are matched literally.
+func Mark(text string, terms []string) string {
+	pattern := pattern(terms)
+	if pattern == nil {
+		return html.EscapeString(text)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:match[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[match[0]:match[1]]))
+		b.WriteString("</mark>")
+		last = match[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// Terms splits a search query into the terms to highlight
+func Terms(query string) []string {
+	return strings.Fields(query)
+}
+
+// pattern returns a case-insensitive regexp matching any of the terms, or nil when
+// there are none
+func pattern(terms []string) *regexp.Regexp {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term != "" {
+			quoted = append(quoted, regexp.QuoteMeta(term))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+
+	// Alternatives are tried in order, so put the longest terms first
+	sort.SliceStable(quoted, func(i, j int) bool {
+		return len(quoted[i]) > len(quoted[j])
+	})
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
@@ -0,0 +1,32 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMark(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		query    string
+		expected string
+	}{
+		{name: "single term", text: "John Doe", query: "doe", expected: "John <mark>Doe</mark>"},
+		{name: "several terms", text: "john.doe@example.com", query: "john example", expected: "<mark>john</mark>.doe@<mark>example</mark>.com"},
+		{name: "every occurrence", text: "Anna Hannah", query: "an", expected: "<mark>An</mark>na H<mark>an</mark>nah"},
+		{name: "longest term first", text: "Johnson", query: "john johnson", expected: "<mark>Johnson</mark>"},
+		{name: "no match", text: "Jane Roe", query: "jhon", expected: "Jane Roe"},
+		{name: "empty query", text: "Jane Roe", query: "  ", expected: "Jane Roe"},
+		{name: "terms are literal", text: "a.b@example.com", query: "a.b", expected: "<mark>a.b</mark>@example.com"},
+		{name: "text is escaped", text: "<script>alert(1)</script>", query: "script", expected: "&lt;<mark>script</mark>&gt;alert(1)&lt;/<mark>script</mark>&gt;"},
+		{name: "unicode case folding", text: "Zoë Ölund", query: "ölund", expected: "Zoë <mark>Ölund</mark>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Mark(tt.text, Terms(tt.query)))
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/labstack/echo/v4"
+)
+
+// contextKey is where Middleware stores the translator chosen for the request
+const contextKey = "i18n_translator"
+
+// supportedLocales lists the languages a catalog may be loaded for. English is the
+// fallback and the message keys are the English messages themselves.
+var supportedLocales = []locales.Translator{en.New(), id.New()}
+
+// Catalog holds the message translations of every supported language
+type Catalog struct {
+	uni *ut.UniversalTranslator
+}
+
+// Load reads the <locale>.json message catalogs in dir. Each catalog is a JSON object
+// mapping an English message to its translation; {0}-style placeholders are filled in
+// with the message parameters and must match between the two.
+func Load(dir string) (*Catalog, error) {
+	uni := ut.New(en.New(), supportedLocales...)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no message catalogs found in %s", dir)
+	}
+
+	for _, path := range paths {
+		locale := strings.TrimSuffix(filepath.Base(path), ".json")
+		trans, found := uni.GetTranslator(locale)
+		if !found {
+			return nil, fmt.Errorf("message catalog %s: unsupported locale %q", path, locale)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("message catalog %s: %w", path, err)
+		}
+
+		for message, translation := range messages {
+			if placeholders(message) != placeholders(translation) {
+				return nil, fmt.Errorf("message catalog %s: translation of %q must use the same placeholders", path, message)
+			}
+			if err := trans.Add(message, translation, false); err != nil {
+				return nil, fmt.Errorf("message catalog %s: %w", path, err)
+			}
+		}
+	}
+
+	return &Catalog{uni: uni}, nil
+}
+
+// Middleware selects the translator for the request from its Accept-Language header,
+// falling back to English when none of the preferred languages is supported
+func (cat *Catalog) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			trans, _ := cat.uni.FindTranslator(AcceptedLanguages(c.Request().Header.Get("Accept-Language"))...)
+			c.Set(contextKey, trans)
+
+			header := c.Response().Header()
+			header.Add(echo.HeaderVary, "Accept-Language")
+			header.Set("Content-Language", strings.ReplaceAll(trans.Locale(), "_", "-"))
+
+			return next(c)
+		}
+	}
+}
+
+// T translates message into the language selected for the request and fills in its
+// placeholders. Messages without a translation are returned in English.
+func T(c echo.Context, message string, params ...string) string {
+	if trans, ok := c.Get(contextKey).(ut.Translator); ok && placeholders(message) <= len(params) {
+		if translated, err := trans.T(message, params...); err == nil {
+			return translated
+		}
+	}
+	return Format(message, params...)
+}
+
+// Format fills the {0}-style placeholders of an untranslated message
+func Format(message string, params ...string) string {
+	for i, param := range params {
+		message = strings.ReplaceAll(message, "{"+strconv.Itoa(i)+"}", param)
+	}
+	return message
+}
+
+// AcceptedLanguages returns the locales of an Accept-Language header from most to least
+// preferred, e.g. "id-ID,en;q=0.8" gives id_ID, id, en. Languages with q=0 are skipped.
+func AcceptedLanguages(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []language
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		languages = append(languages, language{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	accepted := make([]string, 0, len(languages)*2)
+	seen := make(map[string]bool)
+	for _, lang := range languages {
+		base, region, hasRegion := strings.Cut(lang.tag, "-")
+		base = strings.ToLower(base)
+
+		candidates := []string{base}
+		if hasRegion {
+			candidates = []string{base + "_" + strings.ToUpper(region), base}
+		}
+		for _, locale := range candidates {
+			if !seen[locale] {
+				seen[locale] = true
+				accepted = append(accepted, locale)
+			}
+		}
+	}
+	return accepted
+}
+
+// placeholders counts the {n} placeholders of a message
+func placeholders(message string) int {
+	count := 0
+	for strings.Contains(message, "{"+strconv.Itoa(count)+"}") {
+		count++
+	}
+	return count
+}
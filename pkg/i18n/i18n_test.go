@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptedLanguages(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []string
+	}{
+		{header: "", expected: []string{}},
+		{header: "id", expected: []string{"id"}},
+		{header: "id-ID,id;q=0.9,en;q=0.8", expected: []string{"id_ID", "id", "en"}},
+		{header: "en;q=0.5, de-DE", expected: []string{"de_DE", "de", "en"}},
+		{header: "fr;q=0, *;q=0.1, nl", expected: []string{"nl"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.expected, AcceptedLanguages(tt.header))
+		})
+	}
+}
+
+func TestCatalog_Translate(t *testing.T) {
+	catalog, err := Load(filepath.Join("..", "..", "locales"))
+	require.NoError(t, err)
+
+	e := echo.New()
+
+	tests := []struct {
+		name             string
+		acceptLanguage   string
+		message          string
+		params           []string
+		expected         string
+		expectedLanguage string
+	}{
+		{name: "indonesian", acceptLanguage: "id-ID,id;q=0.9", message: "User not found", expected: "Pengguna tidak ditemukan", expectedLanguage: "id"},
+		{name: "indonesian with parameter", acceptLanguage: "id", message: "must be at least {0} characters long", params: []string{"8"}, expected: "minimal 8 karakter", expectedLanguage: "id"},
+		{name: "unsupported language falls back to english", acceptLanguage: "de-DE", message: "User not found", expected: "User not found", expectedLanguage: "en"},
+		{name: "untranslated message", acceptLanguage: "id", message: "Something new", expected: "Something new", expectedLanguage: "id"},
+		{name: "missing parameter", acceptLanguage: "id", message: "must be at least {0} characters long", expected: "must be at least {0} characters long", expectedLanguage: "id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			var translated string
+			handler := catalog.Middleware()(func(c echo.Context) error {
+				translated = T(c, tt.message, tt.params...)
+				return nil
+			})
+
+			require.NoError(t, handler(c))
+			assert.Equal(t, tt.expected, translated)
+			assert.Equal(t, tt.expectedLanguage, rec.Header().Get("Content-Language"))
+		})
+	}
+}
+
+func TestLoad_RejectsMismatchedPlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "id.json"), []byte(`{"must be at least {0} characters long": "minimal karakter"}`), 0644))
+
+	_, err := Load(dir)
+	assert.Error(t, err)
+}
+
+func TestT_WithoutMiddleware(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	assert.Equal(t, "API key is missing the users:write scope", T(c, "API key is missing the {0} scope", "users:write"))
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"echto/pkg/logger"
+	"echto/pkg/problem"
+	"echto/pkg/token"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// ContextKeyUserID is the echo.Context key holding the authenticated user ID
+	ContextKeyUserID = "user_id"
+	// ContextKeyClaims is the echo.Context key holding the verified token claims
+	ContextKeyClaims = "claims"
+)
+
+// RevocationChecker reports whether an otherwise valid token has been revoked, either
+// itself or along with the tokens of its user or, for impersonation, of its actor
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string, userID, actorID uint, issuedAt time.Time) (bool, error)
+}
+
+// SessionTracker reports whether the login session of a token is still active and records its activity
+type SessionTracker interface {
+	TouchSession(ctx context.Context, sessionID string, userID uint, ipAddress string) (bool, error)
+}
+
+// APIKeyAuthenticator resolves an API key to the claims of the user owning it
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (*token.Claims, bool, error)
+}
+
+// JWTAuthConfig defines the config for JWTAuth middleware
+type JWTAuthConfig struct {
+	// Tokens verifies the bearer token signature and expiry
+	Tokens *token.Manager
+	// Revocations is consulted on every request when set
+	Revocations RevocationChecker
+	// APIKeys accepts API keys as bearer credentials when set
+	APIKeys APIKeyAuthenticator
+	// Sessions is consulted for tokens bound to a login session when set
+	Sessions SessionTracker
+}
+
+// JWTAuth returns a middleware that validates bearer tokens from the Authorization header
+func JWTAuth(tokens *token.Manager) echo.MiddlewareFunc {
+	return JWTAuthWithConfig(JWTAuthConfig{Tokens: tokens})
+}
+
+// JWTAuthWithConfig returns a JWTAuth middleware with config
+func JWTAuthWithConfig(config JWTAuthConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			raw, ok := bearerToken(c.Request().Header.Get(echo.HeaderAuthorization))
+			if !ok {
+				return problem.New(http.StatusUnauthorized, "unauthorized", "Missing or malformed bearer token")
+			}
+
+			if config.APIKeys != nil && token.IsAPIKey(raw) {
+				claims, ok, err := config.APIKeys.Authenticate(c.Request().Context(), raw)
+				if err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to authenticate API key")
+					return problem.New(http.StatusInternalServerError, "internal_server_error", "Failed to verify API key").WithInternal(err)
+				}
+				if !ok {
+					return problem.New(http.StatusUnauthorized, "invalid_api_key", "Invalid or revoked API key")
+				}
+
+				c.Set(ContextKeyUserID, claims.UserID)
+				c.Set(ContextKeyClaims, claims)
+
+				return next(c)
+			}
+
+			claims, err := config.Tokens.Parse(raw)
+			if err != nil {
+				if errors.Is(err, token.ErrExpiredToken) {
+					return problem.New(http.StatusUnauthorized, "token_expired", "Token has expired")
+				}
+				return problem.New(http.StatusUnauthorized, "invalid_token", "Invalid token")
+			}
+
+			if config.Revocations != nil {
+				revoked, err := config.Revocations.IsRevoked(c.Request().Context(), claims.Id, claims.UserID, claims.ActorID, time.Unix(claims.IssuedAt, 0))
+				if err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to check token revocation")
+					return problem.New(http.StatusInternalServerError, "internal_server_error", "Failed to verify token").WithInternal(err)
+				}
+				if revoked {
+					return problem.New(http.StatusUnauthorized, "token_revoked", "Token has been revoked")
+				}
+			}
+
+			if config.Sessions != nil && claims.SessionID != "" {
+				active, err := config.Sessions.TouchSession(c.Request().Context(), claims.SessionID, claims.UserID, c.RealIP())
+				if err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to check session")
+					return problem.New(http.StatusInternalServerError, "internal_server_error", "Failed to verify token").WithInternal(err)
+				}
+				if !active {
+					return problem.New(http.StatusUnauthorized, "session_revoked", "Session has been revoked")
+				}
+			}
+
+			c.Set(ContextKeyUserID, claims.UserID)
+			c.Set(ContextKeyClaims, claims)
+
+			return next(c)
+		}
+	}
+}
+
+// UserIDFromContext returns the authenticated user ID set by JWTAuth
+func UserIDFromContext(c echo.Context) (uint, bool) {
+	userID, ok := c.Get(ContextKeyUserID).(uint)
+	return userID, ok
+}
+
+// ClaimsFromContext returns the verified token claims set by JWTAuth
+func ClaimsFromContext(c echo.Context) (*token.Claims, bool) {
+	claims, ok := c.Get(ContextKeyClaims).(*token.Claims)
+	return claims, ok
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header value
+func bearerToken(header string) (string, bool) {
+	scheme, raw, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	raw = strings.TrimSpace(raw)
+	return raw, raw != ""
+}
@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"context"
+	"echto/internal/repository"
+	"echto/pkg/problem"
+	"echto/pkg/token"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// run calls a handler and renders any returned error the way the server's error handler does
+func run(c echo.Context, h echo.HandlerFunc) {
+	if err := h(c); err != nil {
+		problem.HTTPErrorHandler(err, c)
+	}
+}
+
+func TestJWTAuth(t *testing.T) {
+	e := echo.New()
+	tokens := token.NewManager("test-secret", time.Hour)
+	expired := token.NewManager("test-secret", -time.Hour)
+	otherSecret := token.NewManager("other-secret", time.Hour)
+
+	valid, _, _ := tokens.Generate(1, "user", "")
+	expiredToken, _, _ := expired.Generate(1, "user", "")
+	forged, _, _ := otherSecret.Generate(1, "user", "")
+	challenge, _ := tokens.GenerateChallenge(1)
+
+	revocations := repository.NewMemoryRevocationRepository()
+	revokedToken, revokedClaims, _ := tokens.Generate(2, "user", "")
+	_ = revocations.Revoke(context.Background(), revokedClaims.Id, revokedClaims.UserID, time.Unix(revokedClaims.ExpiresAt, 0))
+
+	// A token issued a minute before all tokens of its user were revoked
+	userRevokedToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &token.Claims{
+		UserID: 3,
+		Role:   "user",
+		StandardClaims: jwt.StandardClaims{
+			Id:        "issued-before-revocation",
+			IssuedAt:  time.Now().Add(-time.Minute).Unix(),
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}).SignedString([]byte("test-secret"))
+	_ = revocations.RevokeUser(context.Background(), 3, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name           string
+		header         string
+		expectedStatus int
+	}{
+		{name: "valid token", header: "Bearer " + valid, expectedStatus: http.StatusOK},
+		{name: "missing header", header: "", expectedStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Basic " + valid, expectedStatus: http.StatusUnauthorized},
+		{name: "expired token", header: "Bearer " + expiredToken, expectedStatus: http.StatusUnauthorized},
+		{name: "wrong signature", header: "Bearer " + forged, expectedStatus: http.StatusUnauthorized},
+		{name: "two factor challenge token", header: "Bearer " + challenge, expectedStatus: http.StatusUnauthorized},
+		{name: "revoked token", header: "Bearer " + revokedToken, expectedStatus: http.StatusUnauthorized},
+		{name: "all user tokens revoked", header: "Bearer " + userRevokedToken, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := JWTAuthWithConfig(JWTAuthConfig{
+				Tokens:      tokens,
+				Revocations: revocations,
+			})(func(c echo.Context) error {
+				userID, ok := UserIDFromContext(c)
+				assert.True(t, ok)
+				assert.Equal(t, uint(1), userID)
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			if tt.header != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+// Tokens issued right after all tokens of the user were revoked, such as those of the
+// login following a password change, must not be caught by the revocation
+func TestJWTAuth_IssuedAfterUserRevocation(t *testing.T) {
+	e := echo.New()
+	tokens := token.NewManager("test-secret", time.Hour)
+	revocations := repository.NewMemoryRevocationRepository()
+
+	_ = revocations.RevokeUser(context.Background(), 1, time.Now().Add(time.Hour))
+	issued, _, _ := tokens.Generate(1, "user", "")
+
+	handler := JWTAuthWithConfig(JWTAuthConfig{
+		Tokens:      tokens,
+		Revocations: revocations,
+	})(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+issued)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	run(c, handler)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// Revoking all tokens of an admin, as demoting or deleting them does, also revokes the
+// impersonation tokens they issued
+func TestJWTAuth_ImpersonationActorRevoked(t *testing.T) {
+	e := echo.New()
+	tokens := token.NewManager("test-secret", time.Hour)
+	revocations := repository.NewMemoryRevocationRepository()
+
+	// An impersonation token of user 2 by admin 9, issued a minute before the revocation
+	impersonation := func(actorID uint) string {
+		signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &token.Claims{
+			UserID:  2,
+			Role:    "user",
+			ActorID: actorID,
+			StandardClaims: jwt.StandardClaims{
+				Id:        "impersonation",
+				IssuedAt:  time.Now().Add(-time.Minute).Unix(),
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			},
+		}).SignedString([]byte("test-secret"))
+		return signed
+	}
+	_ = revocations.RevokeUser(context.Background(), 9, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name           string
+		actorID        uint
+		expectedStatus int
+	}{
+		{name: "actor revoked", actorID: 9, expectedStatus: http.StatusUnauthorized},
+		{name: "other actor", actorID: 8, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := JWTAuthWithConfig(JWTAuthConfig{
+				Tokens:      tokens,
+				Revocations: revocations,
+			})(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+impersonation(tt.actorID))
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+// stubAPIKeys accepts a single API key
+type stubAPIKeys struct {
+	key string
+}
+
+func (s stubAPIKeys) Authenticate(ctx context.Context, rawKey string) (*token.Claims, bool, error) {
+	if rawKey != s.key {
+		return nil, false, nil
+	}
+	return &token.Claims{UserID: 1, Role: "user", APIKeyID: 7, Scopes: []string{"users:read"}}, true, nil
+}
+
+func TestJWTAuth_APIKey(t *testing.T) {
+	e := echo.New()
+	tokens := token.NewManager("test-secret", time.Hour)
+	key, _, _, _ := token.NewAPIKey()
+
+	tests := []struct {
+		name           string
+		apiKeys        APIKeyAuthenticator
+		header         string
+		expectedStatus int
+	}{
+		{name: "valid api key", apiKeys: stubAPIKeys{key: key}, header: "Bearer " + key, expectedStatus: http.StatusOK},
+		{name: "unknown api key", apiKeys: stubAPIKeys{key: key}, header: "Bearer " + token.APIKeyPrefix + "unknown", expectedStatus: http.StatusUnauthorized},
+		{name: "api keys not accepted", apiKeys: nil, header: "Bearer " + key, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := JWTAuthWithConfig(JWTAuthConfig{
+				Tokens:  tokens,
+				APIKeys: tt.apiKeys,
+			})(func(c echo.Context) error {
+				claims, ok := ClaimsFromContext(c)
+				assert.True(t, ok)
+				assert.Equal(t, uint(7), claims.APIKeyID)
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+			req.Header.Set(echo.HeaderAuthorization, tt.header)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+// stubSessions knows a fixed set of active sessions
+type stubSessions struct {
+	active map[string]uint
+}
+
+func (s stubSessions) TouchSession(ctx context.Context, sessionID string, userID uint, ipAddress string) (bool, error) {
+	owner, ok := s.active[sessionID]
+	return ok && owner == userID, nil
+}
+
+func TestJWTAuth_Session(t *testing.T) {
+	e := echo.New()
+	tokens := token.NewManager("test-secret", time.Hour)
+	sessions := stubSessions{active: map[string]uint{"session-1": 1}}
+
+	active, _, _ := tokens.Generate(1, "user", "session-1")
+	revoked, _, _ := tokens.Generate(1, "user", "session-2")
+	unbound, _, _ := tokens.Generate(1, "user", "")
+
+	tests := []struct {
+		name           string
+		header         string
+		expectedStatus int
+	}{
+		{name: "active session", header: "Bearer " + active, expectedStatus: http.StatusOK},
+		{name: "revoked session", header: "Bearer " + revoked, expectedStatus: http.StatusUnauthorized},
+		{name: "token without session", header: "Bearer " + unbound, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := JWTAuthWithConfig(JWTAuthConfig{
+				Tokens:   tokens,
+				Sessions: sessions,
+			})(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/sessions", nil)
+			req.Header.Set(echo.HeaderAuthorization, tt.header)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, handler)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
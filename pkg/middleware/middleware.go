@@ -1,7 +1,11 @@
 package middleware
 
 import (
+	"context"
 	"echto/pkg/logger"
+	"echto/pkg/problem"
+	"errors"
+	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -14,14 +18,23 @@ func RequestLogger() echo.MiddlewareFunc {
 		LogURI:    true,
 		LogStatus: true,
 		LogValuesFunc: func(c echo.Context, values middleware.RequestLoggerValues) error {
-			logger.Log.Info().
+			event := logger.Log.Info().
 				Str("method", c.Request().Method).
 				Str("uri", values.URI).
 				Int("status", values.Status).
 				Dur("latency", values.Latency).
 				Str("remote_ip", c.RealIP()).
-				Str("user_agent", c.Request().UserAgent()).
-				Msg("HTTP request")
+				Str("user_agent", c.Request().UserAgent())
+
+			// Attribute requests made under an impersonation token to the admin behind them
+			if claims, ok := ClaimsFromContext(c); ok {
+				event = event.Uint("user_id", claims.UserID)
+				if claims.IsImpersonation() {
+					event = event.Bool("impersonated", true).Uint("actor_id", claims.ActorID)
+				}
+			}
+
+			event.Msg("HTTP request")
 			return nil
 		},
 	})
@@ -41,10 +54,22 @@ func RateLimiter(store middleware.RateLimiterStore) echo.MiddlewareFunc {
 	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
 		Store: store,
 		DenyHandler: func(c echo.Context, identifier string, err error) error {
-			return c.JSON(429, map[string]string{
-				"error":   "rate_limit_exceeded",
-				"message": "Too many requests",
-			})
+			return problem.New(http.StatusTooManyRequests, "rate_limit_exceeded", "Too many requests")
+		},
+	})
+}
+
+// QueryTimeout returns a middleware that cancels the request context, and with it every
+// database query of the request, once timeout has passed. Errors caused by the deadline
+// are reported as 503 request_timeout.
+func QueryTimeout(timeout time.Duration) echo.MiddlewareFunc {
+	return middleware.ContextTimeoutWithConfig(middleware.ContextTimeoutConfig{
+		Timeout: timeout,
+		ErrorHandler: func(err error, c echo.Context) error {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return problem.New(http.StatusServiceUnavailable, "request_timeout", "The request took too long to complete").WithInternal(err)
+			}
+			return err
 		},
 	})
 }
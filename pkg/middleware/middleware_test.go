@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	e := echo.New()
+
+	tests := []struct {
+		name           string
+		handler        echo.HandlerFunc
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name: "completes in time",
+			handler: func(c echo.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "query exceeds the deadline",
+			handler: func(c echo.Context) error {
+				<-c.Request().Context().Done()
+				return errors.Join(errors.New("failed to get users"), c.Request().Context().Err())
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   "request_timeout",
+		},
+		{
+			name: "other errors pass through",
+			handler: func(c echo.Context) error {
+				return echo.ErrNotFound
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "not_found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			run(c, QueryTimeout(10*time.Millisecond)(tt.handler))
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedCode != "" {
+				assert.Contains(t, rec.Body.String(), `"code":"`+tt.expectedCode+`"`)
+			}
+		})
+	}
+}
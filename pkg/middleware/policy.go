@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"echto/pkg/problem"
+	"echto/pkg/token"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// PolicyFunc decides whether the authenticated caller may access the route
+type PolicyFunc func(c echo.Context, claims *token.Claims) bool
+
+// Authorize returns a middleware that lets the request through when any of the
+// policies allows it and responds with 403 otherwise. It must run after JWTAuth.
+func Authorize(policies ...PolicyFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := ClaimsFromContext(c)
+			if !ok {
+				return problem.New(http.StatusUnauthorized, "unauthorized", "Authentication required")
+			}
+
+			for _, policy := range policies {
+				if policy(c, claims) {
+					return next(c)
+				}
+			}
+
+			return problem.New(http.StatusForbidden, "forbidden", "You do not have permission to perform this action")
+		}
+	}
+}
+
+// RequireScope returns a middleware that rejects API key requests whose key was not
+// granted the scope. Interactive sessions are not scoped. It must run after JWTAuth.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := ClaimsFromContext(c)
+			if !ok {
+				return problem.New(http.StatusUnauthorized, "unauthorized", "Authentication required")
+			}
+			if !claims.HasScope(scope) {
+				return problem.New(http.StatusForbidden, "insufficient_scope", "API key is missing the {0} scope").WithParams(scope)
+			}
+			return next(c)
+		}
+	}
+}
+
+// DenyImpersonation returns a middleware that rejects requests made with an
+// impersonation token, guarding operations only the account owner may perform.
+// It must run after JWTAuth.
+func DenyImpersonation() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := ClaimsFromContext(c)
+			if !ok {
+				return problem.New(http.StatusUnauthorized, "unauthorized", "Authentication required")
+			}
+			if claims.IsImpersonation() {
+				return problem.New(http.StatusForbidden, "impersonation_forbidden", "This action is not allowed while impersonating a user")
+			}
+			return next(c)
+		}
+	}
+}
+
+// HasRole allows callers whose token carries one of the given roles
+func HasRole(roles ...string) PolicyFunc {
+	return func(c echo.Context, claims *token.Claims) bool {
+		for _, role := range roles {
+			if claims.Role == role {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsSelf allows callers whose user ID matches the given path parameter
+func IsSelf(param string) PolicyFunc {
+	return func(c echo.Context, claims *token.Claims) bool {
+		id, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			return false
+		}
+		return uint(id) == claims.UserID
+	}
+}
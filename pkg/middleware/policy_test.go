@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"echto/pkg/token"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorize(t *testing.T) {
+	e := echo.New()
+	selfOrAdmin := Authorize(IsSelf("id"), HasRole("admin"))
+
+	tests := []struct {
+		name           string
+		claims         *token.Claims
+		pathID         string
+		expectedStatus int
+	}{
+		{name: "user accessing self", claims: &token.Claims{UserID: 1, Role: "user"}, pathID: "1", expectedStatus: http.StatusOK},
+		{name: "user accessing another user", claims: &token.Claims{UserID: 1, Role: "user"}, pathID: "2", expectedStatus: http.StatusForbidden},
+		{name: "admin accessing another user", claims: &token.Claims{UserID: 1, Role: "admin"}, pathID: "2", expectedStatus: http.StatusOK},
+		{name: "unauthenticated", claims: nil, pathID: "1", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := selfOrAdmin(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/users/"+tt.pathID, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/v1/users/:id")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.pathID)
+			if tt.claims != nil {
+				c.Set(ContextKeyClaims, tt.claims)
+			}
+
+			run(c, handler)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	e := echo.New()
+	canWrite := RequireScope("users:write")
+
+	tests := []struct {
+		name           string
+		claims         *token.Claims
+		expectedStatus int
+	}{
+		{name: "session token", claims: &token.Claims{UserID: 1, Role: "user"}, expectedStatus: http.StatusOK},
+		{name: "api key with scope", claims: &token.Claims{UserID: 1, APIKeyID: 1, Scopes: []string{"users:read", "users:write"}}, expectedStatus: http.StatusOK},
+		{name: "api key without scope", claims: &token.Claims{UserID: 1, APIKeyID: 1, Scopes: []string{"users:read"}}, expectedStatus: http.StatusForbidden},
+		{name: "unauthenticated", claims: nil, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := canWrite(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/users/1", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if tt.claims != nil {
+				c.Set(ContextKeyClaims, tt.claims)
+			}
+
+			run(c, handler)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestDenyImpersonation(t *testing.T) {
+	e := echo.New()
+	notImpersonating := DenyImpersonation()
+
+	tests := []struct {
+		name           string
+		claims         *token.Claims
+		expectedStatus int
+	}{
+		{name: "session token", claims: &token.Claims{UserID: 1, Role: "user"}, expectedStatus: http.StatusOK},
+		{name: "impersonation token", claims: &token.Claims{UserID: 1, Role: "user", ActorID: 2}, expectedStatus: http.StatusForbidden},
+		{name: "unauthenticated", claims: nil, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := notImpersonating(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/users/1/password", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if tt.claims != nil {
+				c.Set(ContextKeyClaims, tt.claims)
+			}
+
+			run(c, handler)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package notifier
+
+import (
+	"echto/pkg/logger"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a notification addressed to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users, e.g. password reset links
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// New returns the notifier for the configured driver, falling back to the log notifier
+func New(driver, filePath string) Notifier {
+	switch strings.ToLower(driver) {
+	case "file":
+		return NewFileNotifier(filePath)
+	case "log", "":
+		return NewLogNotifier()
+	default:
+		logger.Log.Warn().Str("driver", driver).Msg("Unknown notifier driver, using log notifier")
+		return NewLogNotifier()
+	}
+}
+
+type logNotifier struct{}
+
+// NewLogNotifier returns a notifier that writes messages to the application log, for local development
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) Send(msg Message) error {
+	logger.Log.Info().
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Str("body", msg.Body).
+		Msg("Notification")
+	return nil
+}
+
+type fileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileNotifier returns a notifier that appends messages to a file, for local development
+func NewFileNotifier(path string) Notifier {
+	return &fileNotifier{path: path}
+}
+
+func (n *fileNotifier) Send(msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n---\n\n",
+		time.Now().Format(time.RFC3339),
+		msg.To,
+		msg.Subject,
+		msg.Body,
+	)
+	return err
+}
@@ -0,0 +1,200 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider is a minimal OpenID Connect provider serving discovery, keys and a
+// token endpoint that checks PKCE for a single pending authorization code
+type stubProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	kid      string
+	clientID string
+
+	code          string
+	codeChallenge string
+	nonce         string
+	audience      string
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &stubProvider{key: key, kid: "key-1", clientID: "echto"}
+	p.audience = p.clientID
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": p.kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("code") != p.code || CodeChallenge(r.PostForm.Get("code_verifier")) != p.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     p.idToken(t),
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+// authorize simulates the user logging in at the provider and returns the issued code
+func (p *stubProvider) authorize(t *testing.T, authURL string) string {
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+
+	p.code = "code-123"
+	p.codeChallenge = query.Get("code_challenge")
+	p.nonce = query.Get("nonce")
+	return p.code
+}
+
+func (p *stubProvider) idToken(t *testing.T) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            "user-42",
+		"aud":            []string{p.audience},
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          p.nonce,
+		"email":          "jane@example.com",
+		"email_verified": true,
+		"name":           "Jane Doe",
+	})
+	token.Header["kid"] = p.kid
+	signed, err := token.SignedString(p.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func (p *stubProvider) client() *Client {
+	return NewClient(Config{
+		IssuerURL:    p.server.URL,
+		ClientID:     p.clientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	})
+}
+
+func TestClient_AuthorizationCodeFlow(t *testing.T) {
+	provider := newStubProvider(t)
+	client := provider.client()
+
+	verifier, challenge, err := NewPKCE()
+	require.NoError(t, err)
+
+	authURL, err := client.AuthCodeURL(context.Background(), "state-1", "nonce-1", challenge)
+	require.NoError(t, err)
+	assert.Contains(t, authURL, provider.server.URL+"/authorize?")
+	assert.Contains(t, authURL, "code_challenge_method=S256")
+
+	code := provider.authorize(t, authURL)
+
+	identity, err := client.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", identity.Subject)
+	assert.Equal(t, "jane@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "nonce-1", identity.Nonce)
+}
+
+func TestClient_ExchangeRejectsWrongVerifier(t *testing.T) {
+	provider := newStubProvider(t)
+	client := provider.client()
+
+	_, challenge, _ := NewPKCE()
+	authURL, err := client.AuthCodeURL(context.Background(), "state-1", "nonce-1", challenge)
+	require.NoError(t, err)
+	code := provider.authorize(t, authURL)
+
+	otherVerifier, _, _ := NewPKCE()
+	_, err = client.Exchange(context.Background(), code, otherVerifier)
+	assert.ErrorIs(t, err, ErrExchange)
+}
+
+func TestClient_ExchangeRejectsForeignAudience(t *testing.T) {
+	provider := newStubProvider(t)
+	provider.audience = "another-client"
+	client := provider.client()
+
+	verifier, challenge, _ := NewPKCE()
+	authURL, err := client.AuthCodeURL(context.Background(), "state-1", "nonce-1", challenge)
+	require.NoError(t, err)
+	code := provider.authorize(t, authURL)
+
+	_, err = client.Exchange(context.Background(), code, verifier)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestClient_RefreshesKeysOnRotation(t *testing.T) {
+	provider := newStubProvider(t)
+	client := provider.client()
+
+	verifier, challenge, _ := NewPKCE()
+	authURL, _ := client.AuthCodeURL(context.Background(), "state-1", "nonce-1", challenge)
+	code := provider.authorize(t, authURL)
+	_, err := client.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+
+	// The provider rotates its signing key
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider.key = key
+	provider.kid = "key-2"
+
+	verifier, challenge, _ = NewPKCE()
+	authURL, _ = client.AuthCodeURL(context.Background(), "state-2", "nonce-2", challenge)
+	code = provider.authorize(t, authURL)
+	identity, err := client.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+	assert.Equal(t, "nonce-2", identity.Nonce)
+}
+
+func TestClient_DiscoveryIssuerMismatch(t *testing.T) {
+	provider := newStubProvider(t)
+	client := NewClient(Config{IssuerURL: provider.server.URL + "/other", ClientID: "echto"})
+
+	_, err := client.AuthCodeURL(context.Background(), "state", "nonce", "challenge")
+	assert.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// AlgorithmBcrypt stores hashes in the standard "$2a$<cost>$..." format
+	AlgorithmBcrypt = "bcrypt"
+	// AlgorithmArgon2id stores hashes in the PHC format "$argon2id$v=19$m=<KiB>,t=<iterations>,p=<threads>$<salt>$<hash>"
+	AlgorithmArgon2id = "argon2id"
+
+	// BcryptMaxBytes is the longest password bcrypt accepts, in bytes
+	BcryptMaxBytes = 72
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+)
+
+// Config holds the parameters new hashes are created with
+type Config struct {
+	Algorithm         string
+	BcryptCost        int
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// Hasher hashes passwords with the configured algorithm and verifies hashes made
+// with any supported algorithm, so parameters can be raised without forcing resets
+type Hasher struct {
+	config Config
+	// dummyHash is a hash of a random password made with the configured parameters
+	dummyHash string
+}
+
+func NewHasher(config Config) (*Hasher, error) {
+	switch config.Algorithm {
+	case AlgorithmBcrypt:
+		if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case AlgorithmArgon2id:
+		if config.Argon2Memory == 0 || config.Argon2Iterations == 0 || config.Argon2Parallelism == 0 {
+			return nil, errors.New("argon2id memory, iterations and parallelism must be positive")
+		}
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+
+	hasher := &Hasher{config: config}
+
+	// Hash a password nobody knows, to spend the time of a real verification on logins
+	// for accounts that do not exist
+	dummy := make([]byte, 32)
+	if _, err := rand.Read(dummy); err != nil {
+		return nil, err
+	}
+	dummyHash, err := hasher.Hash(base64.RawStdEncoding.EncodeToString(dummy))
+	if err != nil {
+		return nil, err
+	}
+	hasher.dummyHash = dummyHash
+
+	return hasher, nil
+}
+
+// VerifyDummy verifies the password against a hash no password matches. It takes as
+// long as Verify does for a real account, so failed logins do not reveal which
+// accounts exist.
+func (h *Hasher) VerifyDummy(password string) {
+	_, _ = h.Verify(password, h.dummyHash)
+}
+
+// MaxPasswordBytes returns the longest password in bytes the configured algorithm can
+// hash, or 0 when it has no limit
+func (h *Hasher) MaxPasswordBytes() int {
+	if h.config.Algorithm == AlgorithmBcrypt {
+		return BcryptMaxBytes
+	}
+	return 0
+}
+
+// Hash returns a self-describing hash of the password
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.config.Algorithm == AlgorithmArgon2id {
+		return h.hashArgon2id(password)
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.config.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify reports whether the password matches the encoded hash
+func (h *Hasher) Verify(password, encoded string) (bool, error) {
+	if strings.HasPrefix(encoded, "$"+AlgorithmArgon2id+"$") {
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		actual := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(actual, key) == 1, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, err
+}
+
+// NeedsRehash reports whether the encoded hash was made with another algorithm or weaker parameters than configured
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if h.config.Algorithm == AlgorithmArgon2id {
+		params, _, _, err := decodeArgon2id(encoded)
+		if err != nil {
+			return true
+		}
+		return params.memory < h.config.Argon2Memory ||
+			params.iterations < h.config.Argon2Iterations ||
+			params.parallelism < h.config.Argon2Parallelism
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+	return cost < h.config.BcryptCost
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func (h *Hasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.config.Argon2Iterations, h.config.Argon2Memory, h.config.Argon2Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id,
+		argon2.Version,
+		h.config.Argon2Memory,
+		h.config.Argon2Iterations,
+		h.config.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// decodeArgon2id parses a PHC formatted argon2id hash
+func decodeArgon2id(encoded string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,77 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fastBcrypt   = Config{Algorithm: AlgorithmBcrypt, BcryptCost: 4}
+	strongBcrypt = Config{Algorithm: AlgorithmBcrypt, BcryptCost: 5}
+	fastArgon2id = Config{Algorithm: AlgorithmArgon2id, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1}
+)
+
+func TestHasher_HashAndVerify(t *testing.T) {
+	for _, config := range []Config{fastBcrypt, fastArgon2id} {
+		t.Run(config.Algorithm, func(t *testing.T) {
+			hasher, err := NewHasher(config)
+			require.NoError(t, err)
+
+			encoded, err := hasher.Hash("correct horse")
+			require.NoError(t, err)
+			assert.Contains(t, encoded, "$")
+
+			ok, err := hasher.Verify("correct horse", encoded)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = hasher.Verify("battery staple", encoded)
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestHasher_VerifyAcrossAlgorithms(t *testing.T) {
+	bcryptHasher, _ := NewHasher(fastBcrypt)
+	argonHasher, _ := NewHasher(fastArgon2id)
+
+	legacy, err := bcryptHasher.Hash("correct horse")
+	require.NoError(t, err)
+
+	ok, err := argonHasher.Verify("correct horse", legacy)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	bcryptHasher, _ := NewHasher(fastBcrypt)
+	strongHasher, _ := NewHasher(strongBcrypt)
+	argonHasher, _ := NewHasher(fastArgon2id)
+
+	weak, _ := bcryptHasher.Hash("correct horse")
+	argon, _ := argonHasher.Hash("correct horse")
+
+	assert.False(t, bcryptHasher.NeedsRehash(weak))
+	assert.True(t, strongHasher.NeedsRehash(weak), "raised bcrypt cost")
+	assert.True(t, argonHasher.NeedsRehash(weak), "switched to argon2id")
+	assert.False(t, argonHasher.NeedsRehash(argon))
+	assert.True(t, bcryptHasher.NeedsRehash(argon), "switched back to bcrypt")
+}
+
+func TestHasher_MalformedHash(t *testing.T) {
+	hasher, _ := NewHasher(fastArgon2id)
+
+	_, err := hasher.Verify("correct horse", "$argon2id$v=19$m=64$broken")
+	assert.ErrorIs(t, err, ErrMalformedHash)
+}
+
+func TestNewHasher_InvalidConfig(t *testing.T) {
+	_, err := NewHasher(Config{Algorithm: "md5"})
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	_, err = NewHasher(Config{Algorithm: AlgorithmBcrypt, BcryptCost: 1})
+	assert.Error(t, err)
+}
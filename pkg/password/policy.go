@@ -0,0 +1,187 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minUserInfoLength is the shortest name or email fragment a password may not contain
+const minUserInfoLength = 3
+
+// PolicyConfig holds the rules new passwords have to satisfy
+type PolicyConfig struct {
+	MinLength        int
+	MaxLength        int
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	DisallowUserInfo bool
+	// MaxBytes caps the UTF-8 encoded length for hash algorithms that cannot take longer
+	// passwords, as characters outside ASCII take up to 4 bytes each
+	MaxBytes int
+	// BreachedListPath points to a file of SHA-1 hashes of breached passwords, one per
+	// line, optionally followed by ":<count>" as in the Pwned Passwords download
+	BreachedListPath string
+}
+
+// Violation describes a rule a password does not satisfy. Message may refer to Param as {0}.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Policy checks new passwords against the configured rules
+type Policy struct {
+	config   PolicyConfig
+	breached map[string]struct{}
+}
+
+func NewPolicy(config PolicyConfig) (*Policy, error) {
+	policy := &Policy{config: config}
+
+	if config.BreachedListPath != "" {
+		breached, err := loadBreachedList(config.BreachedListPath)
+		if err != nil {
+			return nil, err
+		}
+		policy.breached = breached
+	}
+
+	return policy, nil
+}
+
+// BreachedCount returns the number of breached password hashes loaded
+func (p *Policy) BreachedCount() int {
+	return len(p.breached)
+}
+
+// Check returns every rule the password violates. userInfo holds values such as the
+// name and email of the account that the password must not contain.
+func (p *Policy) Check(password string, userInfo ...string) []Violation {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if p.config.MinLength > 0 && length < p.config.MinLength {
+		violations = append(violations, Violation{
+			Rule:    "min_length",
+			Param:   strconv.Itoa(p.config.MinLength),
+			Message: "must be at least {0} characters long",
+		})
+	}
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		violations = append(violations, Violation{
+			Rule:    "max_length",
+			Param:   strconv.Itoa(p.config.MaxLength),
+			Message: "must be at most {0} characters long",
+		})
+	} else if p.config.MaxBytes > 0 && len(password) > p.config.MaxBytes {
+		violations = append(violations, Violation{
+			Rule:    "max_bytes",
+			Param:   strconv.Itoa(p.config.MaxBytes),
+			Message: "must be at most {0} bytes long",
+		})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.config.RequireUpper && !hasUpper {
+		violations = append(violations, Violation{Rule: "uppercase", Message: "must contain an uppercase letter"})
+	}
+	if p.config.RequireLower && !hasLower {
+		violations = append(violations, Violation{Rule: "lowercase", Message: "must contain a lowercase letter"})
+	}
+	if p.config.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{Rule: "digit", Message: "must contain a digit"})
+	}
+	if p.config.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{Rule: "symbol", Message: "must contain a symbol"})
+	}
+
+	if p.config.DisallowUserInfo && containsUserInfo(password, userInfo) {
+		violations = append(violations, Violation{Rule: "user_info", Message: "must not contain your name or email"})
+	}
+
+	if p.isBreached(password) {
+		violations = append(violations, Violation{Rule: "breached", Message: "has appeared in a data breach, choose another one"})
+	}
+
+	return violations
+}
+
+func (p *Policy) isBreached(password string) bool {
+	if len(p.breached) == 0 {
+		return false
+	}
+	sum := sha1.Sum([]byte(password))
+	_, found := p.breached[strings.ToUpper(hex.EncodeToString(sum[:]))]
+	return found
+}
+
+// containsUserInfo reports whether the password contains the given values, the words
+// of a name or the local part of an email
+func containsUserInfo(password string, userInfo []string) bool {
+	lowered := strings.ToLower(password)
+	for _, info := range userInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+
+		fragments := strings.Fields(info)
+		if local, _, found := strings.Cut(info, "@"); found {
+			fragments = append(fragments, local)
+		}
+
+		for _, fragment := range fragments {
+			if utf8.RuneCountInString(fragment) >= minUserInfoLength && strings.Contains(lowered, fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadBreachedList reads uppercase SHA-1 hex digests from a file into a set
+func loadBreachedList(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		digest, _, _ := strings.Cut(line, ":")
+		if len(digest) != sha1.Size*2 {
+			return nil, fmt.Errorf("invalid SHA-1 digest in breached password list: %q", digest)
+		}
+		breached[strings.ToUpper(digest)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return breached, nil
+}
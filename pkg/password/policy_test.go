@@ -0,0 +1,98 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rules(violations []Violation) []string {
+	names := make([]string, len(violations))
+	for i, v := range violations {
+		names[i] = v.Rule
+	}
+	return names
+}
+
+func TestPolicy_Check(t *testing.T) {
+	policy, err := NewPolicy(PolicyConfig{
+		MinLength:        8,
+		MaxLength:        72,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowUserInfo: true,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		expected []string
+	}{
+		{name: "strong password", password: "Tr0ub4dor&3x", expected: []string{}},
+		{name: "too short", password: "Ab1!", expected: []string{"min_length"}},
+		{name: "only lowercase", password: "lowercaseonly", expected: []string{"uppercase", "digit", "symbol"}},
+		{name: "contains name", password: "Johnny-B-Good1", expected: []string{"user_info"}},
+		{name: "contains email local part", password: "Xjdoe42!xx", expected: []string{"user_info"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := policy.Check(tt.password, "John Doe", "jdoe@example.com")
+			assert.ElementsMatch(t, tt.expected, rules(violations))
+		})
+	}
+}
+
+func TestPolicy_MaxBytes(t *testing.T) {
+	policy, err := NewPolicy(PolicyConfig{MaxLength: 72, MaxBytes: BcryptMaxBytes})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		expected []string
+	}{
+		{name: "ascii at the limit", password: strings.Repeat("a", 72), expected: []string{}},
+		{name: "multibyte characters over the byte limit", password: strings.Repeat("密码", 15), expected: []string{"max_bytes"}},
+		{name: "too many characters", password: strings.Repeat("a", 73), expected: []string{"max_length"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := policy.Check(tt.password, "John Doe", "jdoe@example.com")
+			assert.ElementsMatch(t, tt.expected, rules(violations))
+		})
+	}
+}
+
+func TestPolicy_Breached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	// SHA-1 of "password123" in Pwned Passwords format, and a comment
+	content := "# breached passwords\nCBFDAC6008F9CAB4083784CBD1874F76618D2A97:251682\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	policy, err := NewPolicy(PolicyConfig{BreachedListPath: path})
+	require.NoError(t, err)
+	assert.Equal(t, 1, policy.BreachedCount())
+
+	assert.Equal(t, []string{"breached"}, rules(policy.Check("password123")))
+	assert.Empty(t, policy.Check("password1234"))
+}
+
+func TestNewPolicy_InvalidBreachedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte("not-a-hash\n"), 0o600))
+
+	_, err := NewPolicy(PolicyConfig{BreachedListPath: path})
+	assert.Error(t, err)
+
+	_, err = NewPolicy(PolicyConfig{BreachedListPath: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+}
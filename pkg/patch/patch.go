@@ -0,0 +1,87 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Media types of the supported patch formats
+const (
+	MIMEMergePatch = "application/merge-patch+json"
+	MIMEJSONPatch  = "application/json-patch+json"
+)
+
+var (
+	// ErrMalformed is returned for a patch document that is not valid in its format
+	ErrMalformed = errors.New("malformed patch")
+	// ErrNotApplicable is returned when a JSON patch refers to a location that does
+	// not exist in the document
+	ErrNotApplicable = errors.New("patch not applicable")
+	// ErrTestFailed is returned when a JSON patch test operation does not match
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Apply applies a patch of the given media type to a JSON document
+func Apply(mediaType string, doc, patch []byte) ([]byte, error) {
+	switch mediaType {
+	case MIMEMergePatch:
+		return MergePatch(doc, patch)
+	case MIMEJSONPatch:
+		return JSONPatch(doc, patch)
+	}
+	return nil, fmt.Errorf("%w: unsupported media type %q", ErrMalformed, mediaType)
+}
+
+// MergePatch applies an RFC 7396 JSON merge patch to a JSON document. Members of the
+// patch replace those of the document, objects are merged recursively and null
+// members are removed.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	patchValue, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	docValue, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(docValue, patchValue))
+}
+
+// merge returns target with the merge patch applied
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = merge(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// decode parses a JSON document, keeping numbers as written
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
@@ -0,0 +1,173 @@
+package problem
+
+import (
+	"echto/pkg/i18n"
+	"echto/pkg/logger"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// MIMEApplicationProblemJSON is the media type of RFC 7807 problem details
+	MIMEApplicationProblemJSON = "application/problem+json"
+
+	// typePrefix turns an error code into the problem type URI
+	typePrefix = "urn:echto:problem:"
+)
+
+// FieldError describes why the value of a single request field was rejected.
+// Message is an English message template that may refer to Param as {0}; it is
+// translated and filled in when the problem is rendered.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Details is an RFC 7807 problem details response body
+type Details struct {
+	// Type identifies the kind of problem; "about:blank" when only the status applies
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// RequestID matches the X-Request-ID response header to correlate with server logs
+	RequestID string `json:"request_id,omitempty"`
+	// Code is a stable machine readable error code such as "user_not_found"
+	Code string `json:"code"`
+	// Fields lists the rejected request fields of a validation problem
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// Error is an error that is rendered as problem details by HTTPErrorHandler.
+// Detail is an English message template whose {0}-style placeholders are filled
+// in with Params after it is translated into the language of the request.
+type Error struct {
+	Status int
+	Code   string
+	Detail string
+	Params []string
+	Fields []FieldError
+	// Internal is the cause of the problem; it is logged but never sent to the client
+	Internal error
+}
+
+// New returns a problem with the given status, error code and human readable detail
+func New(status int, code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// WithFields attaches rejected request fields to the problem
+func (e *Error) WithFields(fields []FieldError) *Error {
+	e.Fields = fields
+	return e
+}
+
+// WithParams sets the values of the placeholders in the detail
+func (e *Error) WithParams(params ...string) *Error {
+	e.Params = params
+	return e
+}
+
+// WithInternal attaches the underlying cause to the problem
+func (e *Error) WithInternal(err error) *Error {
+	e.Internal = err
+	return e
+}
+
+func (e *Error) Error() string {
+	detail := i18n.Format(e.Detail, e.Params...)
+	if e.Internal != nil {
+		return e.Code + ": " + detail + ": " + e.Internal.Error()
+	}
+	return e.Code + ": " + detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Internal
+}
+
+// HTTPErrorHandler renders every error reaching Echo as application/problem+json,
+// translating the title, detail and field messages with the i18n middleware.
+// Besides *Error it handles *echo.HTTPError raised by Echo itself, such as unknown
+// routes, unsupported methods and bind errors. Any other error, including panics
+// caught by the Recover middleware, is logged and reported as a 500.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	details := Details{
+		Type:     "about:blank",
+		Status:   http.StatusInternalServerError,
+		Code:     "internal_server_error",
+		Detail:   "An unexpected error occurred",
+		Instance: c.Request().URL.Path,
+	}
+
+	var params []string
+	var problemErr *Error
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.As(err, &problemErr):
+		details.Type = typePrefix + problemErr.Code
+		details.Status = problemErr.Status
+		details.Code = problemErr.Code
+		details.Detail = problemErr.Detail
+		params = problemErr.Params
+		for _, field := range problemErr.Fields {
+			field.Message = i18n.T(c, field.Message, field.Param)
+			details.Fields = append(details.Fields, field)
+		}
+	case errors.As(err, &httpErr):
+		details.Status = httpErr.Code
+		details.Code = statusCode(httpErr.Code)
+		if message, ok := httpErr.Message.(string); ok && httpErr.Code < http.StatusInternalServerError {
+			details.Detail = message
+		} else {
+			details.Detail = http.StatusText(httpErr.Code)
+		}
+	}
+
+	// Log the detail in English before it is translated for the client
+	message := i18n.Format(details.Detail, params...)
+	details.Title = i18n.T(c, http.StatusText(details.Status))
+	details.Detail = i18n.T(c, details.Detail, params...)
+
+	details.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	if details.RequestID == "" {
+		details.RequestID = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+
+	if details.Status >= http.StatusInternalServerError {
+		logger.Log.Error().
+			Err(err).
+			Str("request_id", details.RequestID).
+			Str("uri", c.Request().RequestURI).
+			Msg(message)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(details.Status)
+	} else {
+		err = c.JSON(details.Status, details)
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to write error response")
+	}
+}
+
+// statusCode derives an error code from an HTTP status, e.g. "method_not_allowed"
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
@@ -0,0 +1,132 @@
+package problem
+
+import (
+	"echto/pkg/i18n"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedType   string
+		expectedCode   string
+		expectedDetail string
+	}{
+		{
+			name:           "problem",
+			err:            New(http.StatusNotFound, "user_not_found", "User not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedType:   "urn:echto:problem:user_not_found",
+			expectedCode:   "user_not_found",
+			expectedDetail: "User not found",
+		},
+		{
+			name:           "wrapped problem",
+			err:            fmt.Errorf("handler: %w", New(http.StatusConflict, "email_exists", "Email already exists")),
+			expectedStatus: http.StatusConflict,
+			expectedType:   "urn:echto:problem:email_exists",
+			expectedCode:   "email_exists",
+			expectedDetail: "Email already exists",
+		},
+		{
+			name:           "echo route not found",
+			err:            echo.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedType:   "about:blank",
+			expectedCode:   "not_found",
+			expectedDetail: "Not Found",
+		},
+		{
+			name:           "echo method not allowed",
+			err:            echo.ErrMethodNotAllowed,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedType:   "about:blank",
+			expectedCode:   "method_not_allowed",
+			expectedDetail: "Method Not Allowed",
+		},
+		{
+			name:           "unexpected error",
+			err:            errors.New("pq: connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   "about:blank",
+			expectedCode:   "internal_server_error",
+			expectedDetail: "An unexpected error occurred",
+		},
+		{
+			name:           "internal cause is not exposed",
+			err:            New(http.StatusInternalServerError, "internal_server_error", "Failed to get user").WithInternal(errors.New("pq: connection refused")),
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   "urn:echto:problem:internal_server_error",
+			expectedCode:   "internal_server_error",
+			expectedDetail: "Failed to get user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users/7", nil)
+			rec := httptest.NewRecorder()
+			rec.Header().Set(echo.HeaderXRequestID, "req-123")
+			c := e.NewContext(req, rec)
+
+			HTTPErrorHandler(tt.err, c)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+
+			var details Details
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+			assert.Equal(t, tt.expectedType, details.Type)
+			assert.Equal(t, http.StatusText(tt.expectedStatus), details.Title)
+			assert.Equal(t, tt.expectedStatus, details.Status)
+			assert.Equal(t, tt.expectedCode, details.Code)
+			assert.Equal(t, tt.expectedDetail, details.Detail)
+			assert.Equal(t, "/api/v1/users/7", details.Instance)
+			assert.Equal(t, "req-123", details.RequestID)
+			assert.NotContains(t, rec.Body.String(), "connection refused")
+		})
+	}
+}
+
+func TestHTTPErrorHandler_Localized(t *testing.T) {
+	catalog, err := i18n.Load(filepath.Join("..", "..", "locales"))
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users", nil)
+	req.Header.Set("Accept-Language", "id-ID,id;q=0.9,en;q=0.8")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handler := catalog.Middleware()(func(c echo.Context) error {
+		return New(http.StatusBadRequest, "validation_error", "Request validation failed").WithFields([]FieldError{
+			{Field: "name", Rule: "min", Param: "2", Message: "must be at least {0} characters long"},
+			{Field: "email", Rule: "email", Message: "must be a valid email address"},
+		})
+	})
+	HTTPErrorHandler(handler(c), c)
+
+	var details Details
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+	assert.Equal(t, "Permintaan Tidak Valid", details.Title)
+	assert.Equal(t, "Validasi permintaan gagal", details.Detail)
+	assert.Equal(t, []FieldError{
+		{Field: "name", Rule: "min", Param: "2", Message: "minimal 2 karakter"},
+		{Field: "email", Rule: "email", Message: "harus berupa alamat email yang valid"},
+	}, details.Fields)
+	assert.Equal(t, "id", rec.Header().Get("Content-Language"))
+}
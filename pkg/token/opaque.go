@@ -0,0 +1,57 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// APIKeyPrefix marks opaque tokens that are API keys rather than JWTs
+	APIKeyPrefix = "echto_"
+
+	// apiKeyVisibleLength is how many leading characters of an API key are stored in clear to identify it
+	apiKeyVisibleLength = len(APIKeyPrefix) + 8
+)
+
+// NewID returns a random 128-bit identifier encoded as hex
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewOpaque returns a random URL-safe token together with the hash that should be persisted
+func NewOpaque() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(b)
+	return raw, Hash(raw), nil
+}
+
+// NewAPIKey returns a random API key, the visible prefix identifying it and the hash that should be persisted
+func NewAPIKey() (string, string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	raw := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return raw, raw[:apiKeyVisibleLength], Hash(raw), nil
+}
+
+// IsAPIKey reports whether a bearer credential is an API key
+func IsAPIKey(raw string) bool {
+	return strings.HasPrefix(raw, APIKeyPrefix)
+}
+
+// Hash returns the SHA-256 hex digest of an opaque token
+func Hash(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,61 @@
+package useragent
+
+import "strings"
+
+// match pairs a User-Agent substring with the name it identifies
+type match struct {
+	token string
+	name  string
+}
+
+// browsers are checked in order, since most User-Agent strings also claim to be
+// the engines they derive from (Edge mentions Chrome, Chrome mentions Safari)
+var browsers = []match{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"PostmanRuntime/", "Postman"},
+	{"okhttp/", "OkHttp"},
+	{"Go-http-client/", "Go HTTP client"},
+}
+
+var systems = []match{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// Describe returns a short human readable device name such as "Firefox on Linux"
+// for a User-Agent header value
+func Describe(userAgent string) string {
+	browser := find(browsers, userAgent)
+	system := find(systems, userAgent)
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+func find(matches []match, userAgent string) string {
+	for _, m := range matches {
+		if strings.Contains(userAgent, m.token) {
+			return m.name
+		}
+	}
+	return ""
+}
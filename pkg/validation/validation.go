@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"echto/pkg/problem"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Validator validates request structs for echo's c.Validate and reports
+// violations as a problem listing every rejected field
+type Validator struct {
+	validate *validator.Validate
+}
+
+// New returns a Validator that names fields after their json or query tag
+func New() *Validator {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(fieldName)
+	return &Validator{validate: validate}
+}
+
+// Validate implements echo.Validator
+func (v *Validator) Validate(i interface{}) error {
+	err := v.validate.Struct(i)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return problem.New(http.StatusInternalServerError, "internal_server_error", "Failed to validate request").WithInternal(err)
+	}
+
+	structType := reflect.TypeOf(i)
+	fields := make([]problem.FieldError, len(validationErrs))
+	for n, fieldErr := range validationErrs {
+		param := fieldParam(structType, fieldErr)
+		fields[n] = problem.FieldError{
+			Field:   fieldPath(fieldErr),
+			Rule:    fieldErr.Tag(),
+			Param:   param,
+			Message: message(fieldErr),
+		}
+	}
+
+	return problem.New(http.StatusBadRequest, "validation_error", "Request validation failed").WithFields(fields)
+}
+
+// fieldName returns the name clients use for a struct field: its json tag, or its
+// query tag for query parameters, falling back to the Go field name
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "query"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// fieldPath returns the path of the rejected field without the struct name,
+// e.g. "scopes[0]" for the first scope of an APIKeyCreateRequest
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, found := strings.Cut(fieldErr.Namespace(), ".")
+	if !found {
+		return fieldErr.Field()
+	}
+	return path
+}
+
+// fieldParam returns the parameter of the failed rule. Cross-field rules such as
+// nefield name another field, which is reported by its client name too.
+func fieldParam(structType reflect.Type, fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	if !strings.HasSuffix(fieldErr.Tag(), "field") {
+		return param
+	}
+
+	for structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return param
+	}
+	if field, ok := structType.FieldByName(param); ok {
+		if name := fieldName(field); name != "" {
+			return name
+		}
+	}
+	return param
+}
+
+// message returns the English message template for a failed rule, in the same register
+// as the password policy messages. {0} refers to the rule parameter.
+func message(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "numeric":
+		return "must contain only digits"
+	case "oneof":
+		return "must be one of: {0}"
+	case "nefield":
+		return "must be different from {0}"
+	case "len":
+		if fieldErr.Kind() == reflect.String {
+			return "must be exactly {0} characters long"
+		}
+		return "must contain exactly {0} items"
+	case "min":
+		switch fieldErr.Kind() {
+		case reflect.String:
+			return "must be at least {0} characters long"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return "must contain at least {0} items"
+		}
+		return "must be at least {0}"
+	case "max":
+		switch fieldErr.Kind() {
+		case reflect.String:
+			return "must be at most {0} characters long"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return "must contain at most {0} items"
+		}
+		return "must be at most {0}"
+	}
+	return "is invalid"
+}
@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"echto/pkg/problem"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string   `json:"current_password" validate:"required"`
+	NewPassword     string   `json:"new_password" validate:"required,nefield=CurrentPassword"`
+	Scopes          []string `json:"scopes" validate:"required,min=1,dive,oneof=users:read users:write"`
+	Code            string   `json:"code,omitempty" validate:"omitempty,len=6,numeric"`
+	State           string   `query:"state" validate:"required"`
+}
+
+func TestValidator_Validate(t *testing.T) {
+	v := New()
+
+	tests := []struct {
+		name           string
+		request        changePasswordRequest
+		expectedFields []problem.FieldError
+	}{
+		{
+			name: "valid request",
+			request: changePasswordRequest{
+				CurrentPassword: "old-password",
+				NewPassword:     "new-password",
+				Scopes:          []string{"users:read"},
+				State:           "abc",
+			},
+		},
+		{
+			name: "reports every rejected field by its client name",
+			request: changePasswordRequest{
+				CurrentPassword: "same",
+				NewPassword:     "same",
+				Scopes:          []string{"users:read", "admin"},
+				Code:            "12ab",
+			},
+			expectedFields: []problem.FieldError{
+				{Field: "new_password", Rule: "nefield", Param: "current_password", Message: "must be different from {0}"},
+				{Field: "scopes[1]", Rule: "oneof", Param: "users:read users:write", Message: "must be one of: {0}"},
+				{Field: "code", Rule: "len", Param: "6", Message: "must be exactly {0} characters long"},
+				{Field: "state", Rule: "required", Message: "is required"},
+			},
+		},
+		{
+			name:    "missing list",
+			request: changePasswordRequest{CurrentPassword: "a", NewPassword: "b", State: "abc"},
+			expectedFields: []problem.FieldError{
+				{Field: "scopes", Rule: "required", Message: "is required"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(&tt.request)
+			if tt.expectedFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var problemErr *problem.Error
+			require.True(t, errors.As(err, &problemErr))
+			assert.Equal(t, http.StatusBadRequest, problemErr.Status)
+			assert.Equal(t, "validation_error", problemErr.Code)
+			assert.Equal(t, tt.expectedFields, problemErr.Fields)
+		})
+	}
+}